@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// altSvcMiddleware advertises the HTTP/3 endpoint on every HTTP/1.1 and HTTP/2 response via the
+// Alt-Svc header, so browsers that hit this server over TCP automatically try QUIC on subsequent
+// requests. It advertises both the current `h3` token and the legacy `h3-29` draft token for
+// older clients, and is a no-op (no header emitted) when h3Port is empty — e.g. Cloud Run, where
+// HTTP/3 isn't available at all.
+func altSvcMiddleware(h3Port string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if h3Port == "" {
+			return next
+		}
+
+		value := fmt.Sprintf(`h3=":%s"; ma=86400, h3-29=":%s"; ma=86400`, h3Port, h3Port)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// A request that already arrived over HTTP/3 has nothing to upgrade to.
+			if r.ProtoMajor != 3 {
+				w.Header().Set("Alt-Svc", value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+)
+
+// benchReport holds the aggregated per-protocol metrics produced by runBenchmarkClient.
+type benchReport struct {
+	Protocol          string  `json:"protocol"`
+	Requests          int     `json:"requests"`
+	Errors            int     `json:"errors"`
+	BytesTransferred  int64   `json:"bytes_transferred"`
+	MinLatencyMs      float64 `json:"min_latency_ms"`
+	AvgLatencyMs      float64 `json:"avg_latency_ms"`
+	P50LatencyMs      float64 `json:"p50_latency_ms"`
+	P95LatencyMs      float64 `json:"p95_latency_ms"`
+	P99LatencyMs      float64 `json:"p99_latency_ms"`
+	MaxLatencyMs      float64 `json:"max_latency_ms"`
+	AvgTLSHandshakeMs float64 `json:"avg_tls_handshake_ms"`
+	AvgTTFBMs         float64 `json:"avg_ttfb_ms"`
+	ThroughputRPS     float64 `json:"throughput_rps"`
+}
+
+// benchSample is one request's raw timings, collected by a worker and reduced into a benchReport.
+type benchSample struct {
+	latency      time.Duration
+	tlsHandshake time.Duration
+	ttfb         time.Duration
+	bytes        int64
+	err          error
+}
+
+// runBenchmarkClient is the DEPLOY_MODE=bench-client subcommand: a load generator that drives
+// the H1/H2/H3 endpoints started by runBenchmarkMode and reports per-protocol latency,
+// throughput, error counts and bytes transferred as JSON so CI can compare protocols across
+// deploys.
+//
+// Configuration is via environment variables to match the rest of the DEPLOY_MODE subcommands:
+//
+//	BENCH_TARGETS     comma-separated list of URLs to hit (required)
+//	BENCH_CONCURRENCY number of concurrent workers per protocol (default 10)
+//	BENCH_REQUESTS    total requests to issue per protocol (default 100)
+//	BENCH_QPS         throttle to this many requests/sec per protocol; 0 = unthrottled (default 0)
+//	BENCH_PROTOCOLS   comma-separated subset of h1,h2,h3 to run (default h1,h2,h3)
+func runBenchmarkClient() {
+	targets := splitAndTrim(os.Getenv("BENCH_TARGETS"))
+	if len(targets) == 0 {
+		log.Fatalf("BENCH_TARGETS must name at least one URL to benchmark")
+	}
+
+	concurrency := envInt("BENCH_CONCURRENCY", 10)
+	totalRequests := envInt("BENCH_REQUESTS", 100)
+	qps := envFloat("BENCH_QPS", 0)
+
+	protocols := splitAndTrim(os.Getenv("BENCH_PROTOCOLS"))
+	if len(protocols) == 0 {
+		protocols = []string{"h1", "h2", "h3"}
+	}
+
+	log.Printf("[Bench Client Mode] targets=%v concurrency=%d requests=%d qps=%v protocols=%v",
+		targets, concurrency, totalRequests, qps, protocols)
+
+	reports := make(map[string]*benchReport, len(protocols))
+	for _, proto := range protocols {
+		client := newBenchClient(proto)
+		reports[proto] = runBenchProtocol(client, proto, targets, totalRequests, concurrency, qps)
+		closeBenchClient(client, proto)
+	}
+
+	out, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to marshal benchmark report: %v", err)
+	}
+	fmt.Println(string(out))
+}
+
+// newBenchClient configures an *http.Client pinned to a single protocol, mirroring the
+// client construction in cmd/httpbench so results from both tools are comparable.
+func newBenchClient(protocol string) *http.Client {
+	switch protocol {
+	case "h2":
+		transport := &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+		if err := http2.ConfigureTransport(transport); err != nil {
+			log.Fatalf("failed to configure HTTP/2 transport: %v", err)
+		}
+		return &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	case "h3":
+		return &http.Client{
+			Transport: &http3.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+			Timeout: 30 * time.Second,
+		}
+	default: // h1
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+				MaxIdleConnsPerHost: 100,
+			},
+			Timeout: 30 * time.Second,
+		}
+	}
+}
+
+func closeBenchClient(client *http.Client, protocol string) {
+	if protocol == "h3" {
+		if transport, ok := client.Transport.(*http3.Transport); ok {
+			transport.Close()
+		}
+	}
+}
+
+// runBenchProtocol drives `totalRequests` requests (round-robined across targets) through
+// `concurrency` worker goroutines, optionally gated by a time.Tick throttle when qps > 0, and
+// reduces the resulting samples into a single benchReport.
+func runBenchProtocol(client *http.Client, protocol string, targets []string, totalRequests, concurrency int, qps float64) *benchReport {
+	samples := make([]benchSample, totalRequests)
+
+	jobs := make(chan int, totalRequests)
+	for i := 0; i < totalRequests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	var throttle <-chan time.Time
+	if qps > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / qps))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if throttle != nil {
+					<-throttle
+				}
+				target := targets[i%len(targets)]
+				samples[i] = benchRequest(client, target)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return reduceBenchSamples(protocol, samples, elapsed)
+}
+
+// benchRequest issues a single GET and records latency, TLS handshake time, time-to-first-byte
+// and bytes transferred.
+func benchRequest(client *http.Client, url string) benchSample {
+	reqStart := time.Now()
+
+	var tlsStart, tlsEnd, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsEnd = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	ctx := httptrace.WithClientTrace(context.Background(), trace)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return benchSample{err: err}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return benchSample{latency: time.Since(reqStart), err: err}
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return benchSample{latency: time.Since(reqStart), err: err}
+	}
+
+	sample := benchSample{latency: time.Since(reqStart), bytes: n}
+	if !tlsStart.IsZero() && !tlsEnd.IsZero() {
+		sample.tlsHandshake = tlsEnd.Sub(tlsStart)
+	}
+	if !firstByte.IsZero() {
+		sample.ttfb = firstByte.Sub(reqStart)
+	}
+	if resp.StatusCode >= 400 {
+		sample.err = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return sample
+}
+
+func reduceBenchSamples(protocol string, samples []benchSample, elapsed time.Duration) *benchReport {
+	report := &benchReport{Protocol: protocol, Requests: len(samples)}
+
+	latencies := make([]time.Duration, 0, len(samples))
+	var totalLatency, totalTLS, totalTTFB time.Duration
+
+	for _, s := range samples {
+		if s.err != nil {
+			report.Errors++
+			continue
+		}
+		latencies = append(latencies, s.latency)
+		totalLatency += s.latency
+		totalTLS += s.tlsHandshake
+		totalTTFB += s.ttfb
+		report.BytesTransferred += s.bytes
+	}
+
+	if len(latencies) == 0 {
+		return report
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	n := time.Duration(len(latencies))
+
+	report.MinLatencyMs = msOf(latencies[0])
+	report.MaxLatencyMs = msOf(latencies[len(latencies)-1])
+	report.AvgLatencyMs = msOf(totalLatency / n)
+	report.P50LatencyMs = msOf(benchPercentile(latencies, 50))
+	report.P95LatencyMs = msOf(benchPercentile(latencies, 95))
+	report.P99LatencyMs = msOf(benchPercentile(latencies, 99))
+	report.AvgTLSHandshakeMs = msOf(totalTLS / n)
+	report.AvgTTFBMs = msOf(totalTTFB / n)
+	if elapsed > 0 {
+		report.ThroughputRPS = float64(len(latencies)) / elapsed.Seconds()
+	}
+
+	return report
+}
+
+func benchPercentile(sorted []time.Duration, p int) time.Duration {
+	idx := (p * len(sorted)) / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func msOf(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func splitAndTrim(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid %s=%q, using default %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("invalid %s=%q, using default %v", key, v, def)
+		return def
+	}
+	return f
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// loadOrGenerateCert loads the TLS certificate from certFile/keyFile, or, if they don't exist,
+// generates an ephemeral self-signed certificate so first-time users don't need openssl
+// installed to exercise the HTTP/2 and HTTP/3 listeners. The generated certificate is persisted
+// to disk when its directory is writable so subsequent runs reuse it.
+func loadOrGenerateCert(certFile, keyFile string) (tls.Certificate, error) {
+	if _, err := os.Stat(certFile); err == nil {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+
+	log.Printf("Certificate file %s not found, generating an ephemeral self-signed certificate", certFile)
+
+	certPEM, keyPEM, err := generateSelfSignedCert()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	persistCertIfWritable(certFile, keyFile, certPEM, keyPEM)
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// generateSelfSignedCert creates a self-signed RSA certificate valid for localhost and the
+// common loopback addresses, returning PEM-encoded cert and key bytes.
+func generateSelfSignedCert() (certPEM, keyPEM []byte, err error) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+		IsCA:                  false,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+	return certPEM, keyPEM, nil
+}
+
+// persistCertIfWritable writes the generated cert/key to disk so future runs can reuse it,
+// but never fails the caller — it's a convenience, not a requirement.
+func persistCertIfWritable(certFile, keyFile string, certPEM, keyPEM []byte) {
+	dir := filepath.Dir(certFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Note: could not create %s to persist the generated certificate: %v", dir, err)
+		return
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		log.Printf("Note: could not persist generated certificate to %s: %v", certFile, err)
+		return
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		log.Printf("Note: could not persist generated key to %s: %v", keyFile, err)
+		return
+	}
+	log.Printf("Persisted generated certificate to %s and %s", certFile, keyFile)
+}
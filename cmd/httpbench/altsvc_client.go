@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// altSvcDefaultMaxAge is used when an Alt-Svc entry omits ma=, per RFC 7838's implied default.
+const altSvcDefaultMaxAge = 24 * time.Hour
+
+// altSvcEntry is one cached Alt-Svc advertisement for an origin: an H3 authority ("host:port")
+// valid until expiry.
+type altSvcEntry struct {
+	h3Authority string
+	expiry      time.Time
+}
+
+// altSvcCache holds the most recently advertised H3 endpoint per origin, honoring ma=, so the
+// alt-svc protocol mode can decide whether a given origin has already earned an upgrade to H3.
+type altSvcCache struct {
+	mu      sync.Mutex
+	entries map[string]altSvcEntry
+}
+
+func newAltSvcCache() *altSvcCache {
+	return &altSvcCache{entries: make(map[string]altSvcEntry)}
+}
+
+func (c *altSvcCache) set(origin, h3Authority string, maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[origin] = altSvcEntry{h3Authority: h3Authority, expiry: time.Now().Add(maxAge)}
+}
+
+func (c *altSvcCache) get(origin string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[origin]
+	if !ok || time.Now().After(e.expiry) {
+		return "", false
+	}
+	return e.h3Authority, true
+}
+
+// parseAltSvc extracts the first h3/h3-29 entry and its max-age from an Alt-Svc header value like
+// `h3=":8444"; ma=86400, h3-29=":8444"; ma=86400`.
+func parseAltSvc(header string) (authority string, maxAge time.Duration, ok bool) {
+	for _, entry := range strings.Split(header, ",") {
+		parts := strings.Split(entry, ";")
+		kv := strings.SplitN(strings.TrimSpace(parts[0]), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		proto := strings.TrimSpace(kv[0])
+		if proto != "h3" && proto != "h3-29" {
+			continue
+		}
+
+		maxAge = altSvcDefaultMaxAge
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if secs, found := strings.CutPrefix(param, "ma="); found {
+				if n, err := strconv.Atoi(secs); err == nil {
+					maxAge = time.Duration(n) * time.Second
+				}
+			}
+		}
+		return strings.Trim(strings.TrimSpace(kv[1]), `"`), maxAge, true
+	}
+	return "", 0, false
+}
+
+// rewriteAuthority replaces base's host:port with the Alt-Svc-advertised authority, defaulting to
+// base's own host when the advertisement omits one (e.g. `h3=":8444"` means "same host, port
+// 8444").
+func rewriteAuthority(base *neturl.URL, authority string) string {
+	host := base.Hostname()
+	port := strings.TrimPrefix(authority, ":")
+	if h, p, err := net.SplitHostPort(authority); err == nil {
+		host, port = h, p
+	}
+	rewritten := *base
+	rewritten.Host = net.JoinHostPort(host, port)
+	return rewritten.String()
+}
+
+// runAltSvcBenchmark issues every request as H2 until the origin advertises an H3 endpoint via
+// Alt-Svc; it then switches all remaining requests to H3 and records the upgrade point (request
+// index, elapsed time). With migrate, the QUIC connection's local socket is rebound partway
+// through the post-upgrade H3 requests to exercise connection migration.
+func runAltSvcBenchmark(rawURL string, numRequests int, migrate bool) *BenchmarkResult {
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
+		return &BenchmarkResult{Protocol: "alt-svc", TotalRequests: numRequests, FailedRequests: numRequests}
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+	cache := newAltSvcCache()
+
+	h2Client := createClient("h2", nil)
+	defer closeClient(h2Client, "h2")
+
+	results := make([]TimingResult, 0, numRequests)
+	startTime := time.Now()
+
+	var upgraded bool
+	var upgradeRequestIndex int
+	var upgradeElapsed time.Duration
+	var migrationAttempted bool
+	var migrationSuccessRate float64
+	var postMigrationRTTDelta time.Duration
+
+	for i := 0; i < numRequests; i++ {
+		result, headers := makeRequestWithHeaders(h2Client, rawURL, "h2")
+		results = append(results, result)
+
+		if headers != nil {
+			if h3Authority, maxAge, ok := parseAltSvc(headers.Get("Alt-Svc")); ok {
+				cache.set(origin, h3Authority, maxAge)
+			}
+		}
+
+		h3Authority, ok := cache.get(origin)
+		if !ok {
+			continue
+		}
+
+		upgraded = true
+		upgradeRequestIndex = i + 1
+		upgradeElapsed = time.Since(startTime)
+		h3URL := rewriteAuthority(parsed, h3Authority)
+
+		remaining := numRequests - upgradeRequestIndex
+		if remaining > 0 {
+			if migrate {
+				h3Client, mpc, err := newMigratableH3Client()
+				if err != nil {
+					fmt.Printf("alt-svc: failed to build migratable H3 client: %v\n", err)
+				} else {
+					h3Results, successRate, rttDelta := runWithMigration(h3Client, mpc, h3URL, remaining)
+					results = append(results, h3Results...)
+					migrationAttempted = true
+					migrationSuccessRate = successRate
+					postMigrationRTTDelta = rttDelta
+					closeClient(h3Client, "h3")
+				}
+			} else {
+				h3Client := createClient("h3", nil)
+				for j := 0; j < remaining; j++ {
+					results = append(results, makeRequest(h3Client, h3URL, "h3"))
+				}
+				closeClient(h3Client, "h3")
+			}
+		}
+		break
+	}
+
+	br := aggregateResults("alt-svc", results, time.Since(startTime))
+	br.AltSvcUpgraded = upgraded
+	br.AltSvcUpgradeRequestIndex = upgradeRequestIndex
+	br.AltSvcUpgradeElapsed = upgradeElapsed
+	br.MigrationAttempted = migrationAttempted
+	br.MigrationSuccessRate = migrationSuccessRate
+	br.PostMigrationRTTDelta = postMigrationRTTDelta
+	return br
+}
+
+// migratablePacketConn lets a live QUIC connection's local UDP socket be swapped out mid-run, to
+// simulate a NAT rebinding or a Wi-Fi -> cellular handoff for the -migrate flag.
+type migratablePacketConn struct {
+	mu   sync.RWMutex
+	conn net.PacketConn
+}
+
+func newMigratablePacketConn(conn net.PacketConn) *migratablePacketConn {
+	return &migratablePacketConn{conn: conn}
+}
+
+func (m *migratablePacketConn) current() net.PacketConn {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.conn
+}
+
+// rebind closes the current local socket and replaces it with a freshly bound one, simulating the
+// local address change a NAT rebind or a Wi-Fi -> cellular handoff would cause.
+func (m *migratablePacketConn) rebind() error {
+	newConn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	old := m.conn
+	m.conn = newConn
+	m.mu.Unlock()
+	return old.Close()
+}
+
+func (m *migratablePacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	return m.current().ReadFrom(p)
+}
+
+func (m *migratablePacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return m.current().WriteTo(p, addr)
+}
+
+func (m *migratablePacketConn) Close() error                  { return m.current().Close() }
+func (m *migratablePacketConn) LocalAddr() net.Addr           { return m.current().LocalAddr() }
+func (m *migratablePacketConn) SetDeadline(t time.Time) error { return m.current().SetDeadline(t) }
+func (m *migratablePacketConn) SetReadDeadline(t time.Time) error {
+	return m.current().SetReadDeadline(t)
+}
+func (m *migratablePacketConn) SetWriteDeadline(t time.Time) error {
+	return m.current().SetWriteDeadline(t)
+}
+
+// newMigratableH3Client builds an H3 client whose QUIC transport is bound to a migratablePacketConn,
+// so its local socket can be rebound mid-run via mpc.rebind().
+func newMigratableH3Client() (*http.Client, *migratablePacketConn, error) {
+	pc, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	mpc := newMigratablePacketConn(pc)
+	qt := &quic.Transport{Conn: mpc}
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}}
+	transport.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, quicCfg *quic.Config) (quic.EarlyConnection, error) {
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return qt.DialEarly(ctx, udpAddr, tlsCfg, quicCfg)
+	}
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, mpc, nil
+}
+
+// runWithMigration drives numRequests sequential requests over client (whose transport is backed
+// by mpc), rebinding the local UDP socket halfway through to simulate a NAT rebind / Wi-Fi ->
+// cellular handoff, then reports the fraction of post-rebind requests that succeeded and the RTT
+// delta the rebind introduced. It does not distinguish a request that survived on the migrated
+// connection from one that succeeded after a fresh handshake.
+func runWithMigration(client *http.Client, mpc *migratablePacketConn, url string, numRequests int) (results []TimingResult, successRate float64, rttDelta time.Duration) {
+	results = make([]TimingResult, 0, numRequests)
+	rebindAt := numRequests / 2
+
+	var preRTT, postRTT time.Duration
+	var preSuccess, postSuccess, postTotal int
+
+	for i := 0; i < numRequests; i++ {
+		if i == rebindAt {
+			if err := mpc.rebind(); err != nil {
+				fmt.Printf("migration: rebind failed: %v\n", err)
+			}
+		}
+
+		result := makeRequest(client, url, "h3")
+		results = append(results, result)
+
+		if i < rebindAt {
+			if result.Error == nil {
+				preRTT += result.TotalTime
+				preSuccess++
+			}
+			continue
+		}
+		postTotal++
+		if result.Error == nil {
+			postRTT += result.TotalTime
+			postSuccess++
+		}
+	}
+
+	if postTotal > 0 {
+		successRate = float64(postSuccess) / float64(postTotal)
+	}
+	if preSuccess > 0 && postSuccess > 0 {
+		rttDelta = postRTT/time.Duration(postSuccess) - preRTT/time.Duration(preSuccess)
+	}
+	return results, successRate, rttDelta
+}
+
+// runH3MigrationBenchmark is the plain-h3 (non-alt-svc) entry point for -migrate: every request
+// goes straight to H3, and the local socket is rebound halfway through the run.
+func runH3MigrationBenchmark(url string, numRequests int) *BenchmarkResult {
+	client, mpc, err := newMigratableH3Client()
+	if err != nil {
+		fmt.Printf("migration: failed to set up migratable H3 client, falling back to a plain H3 run: %v\n", err)
+		return runBenchmark(url, "h3", numRequests, 1, nil)
+	}
+	defer closeClient(client, "h3")
+
+	startTime := time.Now()
+	results, successRate, rttDelta := runWithMigration(client, mpc, url, numRequests)
+
+	br := aggregateResults("h3", results, time.Since(startTime))
+	br.MigrationAttempted = true
+	br.MigrationSuccessRate = successRate
+	br.PostMigrationRTTDelta = rttDelta
+	return br
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// regressionPValueThreshold is the significance level below which a median shift is trusted to be
+// real rather than noise, before -regression-threshold's magnitude check is even applied.
+const regressionPValueThreshold = 0.05
+
+// bootstrapIterations is the resample count for bootstrapMedianDiffCI's percentile-method CI.
+const bootstrapIterations = 10000
+
+// loadSamplesFromFile reads a prior -o result file and returns its per-protocol samples_ms, the
+// only field -compare needs from a baseline run.
+func loadSamplesFromFile(path string) (map[string][]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Results map[string]struct {
+			SamplesMs []float64 `json:"samples_ms"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	samples := make(map[string][]float64, len(parsed.Results))
+	for proto, r := range parsed.Results {
+		samples[proto] = r.SamplesMs
+	}
+	return samples, nil
+}
+
+func sortedCopy(samples []float64) []float64 {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func percentileFloat(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p / 100 * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// mannWhitneyU runs the Mann-Whitney U test (with tie correction) on two independent samples and
+// returns the U statistic and a two-sided p-value from the normal approximation. It's the
+// nonparametric test appropriate here: latency samples are never normally distributed, so a t-test
+// would misjudge significance on the long tail this tool actually cares about.
+func mannWhitneyU(a, b []float64) (u, pValue float64) {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type labeled struct {
+		value float64
+		group int
+	}
+	combined := make([]labeled, 0, n1+n2)
+	for _, v := range a {
+		combined = append(combined, labeled{v, 0})
+	}
+	for _, v := range b {
+		combined = append(combined, labeled{v, 1})
+	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].value < combined[j].value })
+
+	ranks := make([]float64, len(combined))
+	var tieCorrection float64
+	i := 0
+	for i < len(combined) {
+		j := i
+		for j < len(combined) && combined[j].value == combined[i].value {
+			j++
+		}
+		avgRank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			ranks[k] = avgRank
+		}
+		tieCount := float64(j - i)
+		tieCorrection += tieCount*tieCount*tieCount - tieCount
+		i = j
+	}
+
+	var rankSumA float64
+	for idx, l := range combined {
+		if l.group == 0 {
+			rankSumA += ranks[idx]
+		}
+	}
+
+	u1 := rankSumA - float64(n1*(n1+1))/2
+	u2 := float64(n1*n2) - u1
+	u = math.Min(u1, u2)
+
+	nTotal := float64(n1 + n2)
+	meanU := float64(n1*n2) / 2
+	varU := float64(n1*n2) / 12 * (nTotal + 1 - tieCorrection/(nTotal*(nTotal-1)))
+	if varU <= 0 {
+		return u, 1
+	}
+
+	z := (u - meanU) / math.Sqrt(varU)
+	pValue = 2 * (1 - normalCDF(math.Abs(z)))
+	return u, math.Min(pValue, 1)
+}
+
+func normalCDF(z float64) float64 {
+	return 0.5 * (1 + math.Erf(z/math.Sqrt2))
+}
+
+// bootstrapMedianDiffCI resamples both groups with replacement bootstrapIterations times and
+// returns a 95% percentile-method confidence interval on median(current) - median(baseline).
+func bootstrapMedianDiffCI(baseline, current []float64) (lo, hi float64) {
+	if len(baseline) == 0 || len(current) == 0 {
+		return 0, 0
+	}
+
+	// Seed once from the sample contents (so the CI is reproducible for a given input pair) and
+	// then let a single splitmix64 stream advance across every resample call, so iteration i and
+	// i+1 draw from different states instead of each re-deriving the same seed from the same data.
+	rng := newSplitMix64(seedFromSamples(baseline, current))
+
+	diffs := make([]float64, bootstrapIterations)
+	for i := 0; i < bootstrapIterations; i++ {
+		diffs[i] = median(sortedCopy(resample(current, rng))) - median(sortedCopy(resample(baseline, rng)))
+	}
+	sort.Float64s(diffs)
+	return percentileFloat(diffs, 2.5), percentileFloat(diffs, 97.5)
+}
+
+// splitMix64 is a small, fast PRNG stream used for the bootstrap resampling. It is seeded from
+// the samples themselves (seedFromSamples), so a given input pair always yields the same CI, and
+// it advances its state on every draw instead of re-deriving the same seed on every call.
+type splitMix64 struct {
+	state uint64
+}
+
+func newSplitMix64(seed uint64) *splitMix64 {
+	return &splitMix64{state: seed}
+}
+
+func (s *splitMix64) next() uint64 {
+	s.state += 0x9E3779B97F4A7C15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// seedFromSamples derives a deterministic starting seed from both sample sets, so a given
+// (baseline, current) pair always reports the same CI across repeated invocations.
+func seedFromSamples(baseline, current []float64) uint64 {
+	state := uint64(1469598103934665603)
+	for _, v := range baseline {
+		state ^= math.Float64bits(v)
+		state *= 1099511628211
+	}
+	for _, v := range current {
+		state ^= math.Float64bits(v)
+		state *= 1099511628211
+	}
+	return state
+}
+
+// resample draws len(samples) values from samples with replacement, advancing rng on every draw.
+func resample(samples []float64, rng *splitMix64) []float64 {
+	n := len(samples)
+	out := make([]float64, n)
+	for i := 0; i < n; i++ {
+		out[i] = samples[rng.next()%uint64(n)]
+	}
+	return out
+}
+
+// compareVerdict is one protocol's row in the -compare verdict table.
+type compareVerdict struct {
+	Protocol      string
+	BaselineN     int
+	CurrentN      int
+	BaselineMs    float64
+	CurrentMs     float64
+	DeltaPercent  float64
+	CILowMs       float64
+	CIHighMs      float64
+	PValue        float64
+	IsRegression  bool
+	InsufficientN bool
+}
+
+// compareProtocol judges one protocol's current samples against its baseline samples: a
+// regression is a statistically significant (p<0.05) median increase of at least thresholdPercent.
+func compareProtocol(protocol string, baseline, current []float64, thresholdPercent float64) compareVerdict {
+	v := compareVerdict{Protocol: protocol, BaselineN: len(baseline), CurrentN: len(current)}
+	if len(baseline) < 2 || len(current) < 2 {
+		v.InsufficientN = true
+		return v
+	}
+
+	baseMedian := median(sortedCopy(baseline))
+	curMedian := median(sortedCopy(current))
+	v.BaselineMs = baseMedian
+	v.CurrentMs = curMedian
+	if baseMedian > 0 {
+		v.DeltaPercent = (curMedian - baseMedian) / baseMedian * 100
+	}
+	v.CILowMs, v.CIHighMs = bootstrapMedianDiffCI(baseline, current)
+	_, v.PValue = mannWhitneyU(baseline, current)
+
+	v.IsRegression = v.PValue < regressionPValueThreshold && v.DeltaPercent >= thresholdPercent
+	return v
+}
+
+// runComparison loads baselineFile's samples_ms, judges every protocol present in both the
+// baseline and the current results, prints a verdict table, and reports whether any protocol
+// regressed.
+func runComparison(baselineFile string, results map[string]*BenchmarkResult, thresholdPercent float64) (bool, error) {
+	baselineSamples, err := loadSamplesFromFile(baselineFile)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                    REGRESSION COMPARISON                          ║")
+	fmt.Printf("║ Baseline: %-58s║\n", baselineFile)
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Protocol\tBaseline (ms)\tCurrent (ms)\tΔ%\t95% CI (ms)\tp-value\tVerdict")
+
+	anyRegression := false
+	for _, proto := range []string{"h1", "h2", "h3", "alt-svc"} {
+		r, ok := results[proto]
+		if !ok {
+			continue
+		}
+		base, ok := baselineSamples[proto]
+		if !ok {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\tno baseline\n", proto)
+			continue
+		}
+
+		v := compareProtocol(proto, base, r.Samples, thresholdPercent)
+		if v.InsufficientN {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\tinsufficient samples\n", proto)
+			continue
+		}
+
+		verdict := "ok"
+		if v.IsRegression {
+			verdict = "REGRESSION"
+			anyRegression = true
+		} else if v.DeltaPercent <= -thresholdPercent && v.PValue < regressionPValueThreshold {
+			verdict = "improved"
+		}
+
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%+.1f%%\t[%+.2f, %+.2f]\t%.4f\t%s\n",
+			v.Protocol, v.BaselineMs, v.CurrentMs, v.DeltaPercent, v.CILowMs, v.CIHighMs, v.PValue, verdict)
+	}
+	w.Flush()
+
+	return anyRegression, nil
+}
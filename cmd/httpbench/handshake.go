@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+)
+
+// Handshake modes for -handshake-mode. "cold" forces a fresh transport (and thus a fresh
+// handshake) per request; "warm" reuses one client/transport for the whole run, same as the
+// default benchmark; "0rtt" persists a TLS session cache across requests but opens a fresh
+// connection each time, so a resumed session can carry early (0-RTT) data.
+const (
+	handshakeCold = "cold"
+	handshakeWarm = "warm"
+	handshake0RTT = "0rtt"
+)
+
+// runHandshakeBenchmark drives numRequests sequentially issued requests (in cold and 0rtt modes
+// each on its own connection, so each one's handshake behavior can be observed in isolation; in
+// warm mode on one reused client) and reports a first-connection vs. reused-connection latency
+// breakdown, plus how often 0-RTT was actually accepted.
+func runHandshakeBenchmark(url, protocol string, numRequests int, mode string) *BenchmarkResult {
+	results := make([]TimingResult, 0, numRequests)
+
+	switch protocol {
+	case "h3":
+		results = runH3HandshakeRequests(url, numRequests, mode)
+	default:
+		results = runH1H2HandshakeRequests(url, protocol, numRequests, mode)
+	}
+
+	br := aggregateResults(protocol, results, 0)
+	br.HandshakeMode = mode
+	addHandshakeBreakdown(br, results)
+	return br
+}
+
+func addHandshakeBreakdown(br *BenchmarkResult, results []TimingResult) {
+	var firstTotal, reusedTotal time.Duration
+	for _, r := range results {
+		if r.Error != nil {
+			continue
+		}
+		if r.Used0RTT {
+			br.ZeroRTTAcceptedCount++
+		}
+		if r.ConnectionReused {
+			br.ReusedConnCount++
+			reusedTotal += r.TotalTime
+		} else {
+			br.FirstConnCount++
+			firstTotal += r.TotalTime
+		}
+	}
+	if br.FirstConnCount > 0 {
+		br.AvgFirstConnTime = firstTotal / time.Duration(br.FirstConnCount)
+	}
+	if br.ReusedConnCount > 0 {
+		br.AvgReusedConnTime = reusedTotal / time.Duration(br.ReusedConnCount)
+	}
+}
+
+// runH3HandshakeRequests issues numRequests HTTP/3 requests according to mode. Unlike the
+// closed-loop benchmark, cold and 0rtt give each request its own *http3.Transport (and thus its
+// own QUIC connection) so their handshake behavior is actually exercised instead of silently
+// reusing one connection for the whole run; warm keeps one transport for the whole run.
+func runH3HandshakeRequests(url string, numRequests int, mode string) []TimingResult {
+	results := make([]TimingResult, 0, numRequests)
+
+	var sessionCache tls.ClientSessionCache
+	if mode == handshake0RTT {
+		sessionCache = tls.NewLRUClientSessionCache(numRequests)
+	}
+
+	// warm mode intentionally keeps one transport (and connection) alive for the whole run; its
+	// holder tracks whichever connection that transport last dialed, so reuse is observed.
+	var warmClient *http.Client
+	var warmHolder *quicConnHolder
+	if mode == handshakeWarm {
+		warmHolder = &quicConnHolder{}
+		warmClient = newH3HandshakeClient(nil, warmHolder)
+	}
+
+	for i := 0; i < numRequests; i++ {
+		client, connHolder := warmClient, warmHolder
+		if client == nil {
+			connHolder = &quicConnHolder{}
+			client = newH3HandshakeClient(sessionCache, connHolder)
+		}
+
+		prevConn := connHolder.current()
+		result := makeRequest(client, url, "h3")
+		if result.Error == nil {
+			// Reused: the previous request's connection served this one, or a fresh one resumed a session.
+			conn := connHolder.current()
+			result.ConnectionReused = conn != nil && (conn == prevConn || conn.ConnectionState().TLS.DidResume)
+			result.Used0RTT = connHolder.used0RTT()
+		}
+		results = append(results, result)
+
+		if client != warmClient {
+			if transport, ok := client.Transport.(*http3.Transport); ok {
+				transport.Close()
+			}
+		}
+	}
+
+	if warmClient != nil {
+		if transport, ok := warmClient.Transport.(*http3.Transport); ok {
+			transport.Close()
+		}
+	}
+
+	return results
+}
+
+// quicConnHolder captures the quic.EarlyConnection its transport most recently dialed, so the
+// handshake benchmark can tell whether a request reused it and read its
+// ConnectionState().Used0RTT after the request completes -- the
+// client's *http.Response carries no signal of whether the server actually accepted early data,
+// so "not the first iteration of the loop" is not a substitute for actually observing it.
+type quicConnHolder struct {
+	mu   sync.Mutex
+	conn quic.EarlyConnection
+}
+
+func (h *quicConnHolder) set(conn quic.EarlyConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conn = conn
+}
+
+func (h *quicConnHolder) current() quic.EarlyConnection {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.conn
+}
+
+func (h *quicConnHolder) used0RTT() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		return false
+	}
+	return h.conn.ConnectionState().Used0RTT
+}
+
+// newH3HandshakeClient builds an H3 client for the handshake benchmark. When holder is non-nil,
+// the transport's Dial is overridden to capture the dialed connection so its reuse and actual
+// 0-RTT acceptance can be read back afterward.
+func newH3HandshakeClient(sessionCache tls.ClientSessionCache, holder *quicConnHolder) *http.Client {
+	tlsConfig := &tls.Config{InsecureSkipVerify: false}
+	if sessionCache != nil {
+		tlsConfig.ClientSessionCache = sessionCache
+	}
+	quicConfig := &quic.Config{Allow0RTT: sessionCache != nil}
+
+	transport := &http3.Transport{
+		TLSClientConfig: tlsConfig,
+		QUICConfig:      quicConfig,
+	}
+	if holder != nil {
+		transport.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, quicCfg *quic.Config) (quic.EarlyConnection, error) {
+			conn, err := quic.DialAddrEarly(ctx, addr, tlsCfg, quicCfg)
+			if err != nil {
+				return nil, err
+			}
+			holder.set(conn)
+			return conn, nil
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}
+}
+
+// runH1H2HandshakeRequests is the TLS equivalent for h1/h2: warm reuses one client (and its
+// connection pool / session cache) for the whole run; cold and 0rtt each open a fresh connection
+// per request, differing only in whether a session cache carries tickets across those
+// connections (see the mode switch below).
+func runH1H2HandshakeRequests(url, protocol string, numRequests int, mode string) []TimingResult {
+	results := make([]TimingResult, 0, numRequests)
+
+	// warm keeps one client (and its pooled connection) alive for the whole run. cold and 0rtt
+	// both open a fresh connection per request instead: cold with no session cache at all, so
+	// every request pays a full handshake with nothing to resume; 0rtt with a session cache
+	// shared across iterations, so each fresh connection can still present a ticket an earlier
+	// request's handshake left behind.
+	var warmClient *http.Client
+	var sharedSessionCache tls.ClientSessionCache
+	switch mode {
+	case handshakeWarm:
+		sharedSessionCache = tls.NewLRUClientSessionCache(numRequests)
+		warmClient = newH1H2HandshakeClient(protocol, sharedSessionCache)
+	case handshake0RTT:
+		sharedSessionCache = tls.NewLRUClientSessionCache(numRequests)
+	}
+
+	for i := 0; i < numRequests; i++ {
+		client := warmClient
+		if client == nil {
+			client = newH1H2HandshakeClient(protocol, sharedSessionCache)
+		}
+
+		result := makeRequest(client, url, protocol)
+		// A pooled-connection reuse skips the TLS handshake trace entirely (no new handshake at
+		// all); a fresh handshake that resumes the session via a ticket still reports TLSResumed.
+		// Either one means this request didn't pay a full fresh handshake, unlike plain i>0, which
+		// can't tell a real reuse from a silently re-negotiated connection.
+		result.ConnectionReused = result.Error == nil && (result.TLSHandshake == 0 || result.TLSResumed)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func newH1H2HandshakeClient(protocol string, sessionCache tls.ClientSessionCache) *http.Client {
+	tlsConfig := &tls.Config{ClientSessionCache: sessionCache}
+
+	if protocol == "h2" {
+		return &http.Client{
+			Transport: &http2.Transport{TLSClientConfig: tlsConfig},
+			Timeout:   30 * time.Second,
+		}
+	}
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   30 * time.Second,
+	}
+}
@@ -23,15 +23,34 @@ import (
 
 // TimingResult holds all timing metrics for a single request
 type TimingResult struct {
-	Protocol        string
-	DNSLookup       time.Duration
-	TCPConnect      time.Duration
-	TLSHandshake    time.Duration
-	TimeToFirstByte time.Duration
-	ContentTransfer time.Duration
-	TotalTime       time.Duration
-	StatusCode      int
-	Error           error
+	Protocol         string
+	DNSLookup        time.Duration
+	TCPConnect       time.Duration
+	TLSHandshake     time.Duration
+	TimeToFirstByte  time.Duration
+	ContentTransfer  time.Duration
+	TotalTime        time.Duration
+	StatusCode       int
+	BytesTransferred int64
+	Error            error
+
+	// IntendedStart and ActualStart are only populated by the open-model generator
+	// (runOpenModelBenchmark). CorrectedTotalTime measures from IntendedStart instead of
+	// ActualStart, which is what coordinated-omission-corrected latency requires: a request
+	// queued behind a slow one must count that queueing time against it.
+	IntendedStart      time.Time
+	ActualStart        time.Time
+	CorrectedTotalTime time.Duration
+
+	// Populated only by runHandshakeBenchmark (-handshake-mode): whether this request reused an
+	// existing connection/session (vs. paying a fresh handshake) and, for h3 0rtt mode, whether
+	// the server actually accepted early data. ConnectionReused and TLSResumed are observed
+	// (from the trace below / the QUIC connection's ConnectionState), not inferred from "not the
+	// first iteration of the loop" -- a handshake failure or fallback would otherwise be
+	// miscounted as reuse.
+	ConnectionReused bool
+	TLSResumed       bool
+	Used0RTT         bool
 }
 
 // BenchmarkResult holds aggregated results for a protocol
@@ -54,12 +73,52 @@ type BenchmarkResult struct {
 	P95TotalTime time.Duration
 	P99TotalTime time.Duration
 
+	// Histograms back the Prometheus/OpenMetrics export (-prom, -promhttp); nil unless enabled.
+	Histograms *protocolHistograms
+
 	// Min/Max
 	MinTotalTime time.Duration
 	MaxTotalTime time.Duration
 
 	// Throughput
 	RequestsPerSecond float64
+
+	// Open-model fields, only populated when the benchmark was run with -rate. Corrected
+	// percentiles measure from the intended (scheduled) send time rather than the actual one,
+	// so they reveal tail latency that a closed-loop generator hides (coordinated omission).
+	OpenModel        bool
+	TargetRate       float64
+	P50CorrectedTime time.Duration
+	P95CorrectedTime time.Duration
+	P99CorrectedTime time.Duration
+
+	// Populated only when run via runHandshakeBenchmark (-handshake-mode): a breakdown of
+	// latency for requests that paid a fresh handshake vs. those that reused a connection or
+	// session, plus how often 0-RTT early data was actually accepted by the server.
+	HandshakeMode        string
+	FirstConnCount       int
+	ReusedConnCount      int
+	AvgFirstConnTime     time.Duration
+	AvgReusedConnTime    time.Duration
+	ZeroRTTAcceptedCount int
+
+	// Populated only by the alt-svc protocol mode: whether (and when) the run upgraded from H2
+	// to an Alt-Svc-advertised H3 endpoint.
+	AltSvcUpgraded            bool
+	AltSvcUpgradeRequestIndex int
+	AltSvcUpgradeElapsed      time.Duration
+
+	// Populated only when -migrate rebound the QUIC PacketConn mid-run (h3 or a post-upgrade
+	// alt-svc run): the fraction of requests after the rebind that succeeded, and how much the
+	// rebind moved average latency. Whether a post-rebind request needed a fresh handshake is
+	// not tracked.
+	MigrationAttempted    bool
+	MigrationSuccessRate  float64
+	PostMigrationRTTDelta time.Duration
+
+	// Samples holds each successful request's TotalTime in milliseconds, for -compare's
+	// Mann-Whitney U test and bootstrap CI; nil unless the run succeeded at least once.
+	Samples []float64
 }
 
 func main() {
@@ -68,8 +127,29 @@ func main() {
 	concurrency := flag.Int("c", 10, "Number of concurrent requests")
 	protocols := flag.String("p", "h1,h2,h3", "Protocols to test (comma-separated: h1,h2,h3)")
 	outputDir := flag.String("o", "", "Output directory to save JSON results (optional)")
+	rate := flag.Float64("rate", 0, "Open-model: schedule requests at this fixed rate (req/sec) independent of in-flight completions, instead of the closed-loop -c semaphore")
+	promFile := flag.String("prom", "", "Write latency histograms and counters to this file in Prometheus exposition format (optional)")
+	promHTTP := flag.String("promhttp", "", "Serve live Prometheus metrics on this address (e.g. :9090) while the benchmark runs (optional)")
+	handshakeMode := flag.String("handshake-mode", "", "cold|warm|0rtt: run requests sequentially, each on a fresh connection (cold, 0rtt) or all on one reused connection (warm), instead of the closed-loop run, and report first-connection vs. reused-connection latency (optional)")
+	netemSpec := flag.String("netem", "", "Emulate network conditions, e.g. \"rtt=100ms,jitter=10ms,loss=1%,bandwidth=10Mbit\" (optional)")
+	netemScenarios := flag.String("netem-scenarios", "", "Run the full benchmark matrix once per comma-separated netem preset (LAN,3G,4G,satellite,lossy-wifi) instead of a single run (optional)")
+	migrate := flag.Bool("migrate", false, "Rebind the QUIC connection's local UDP socket partway through an h3 or alt-svc run, simulating a NAT rebind / Wi-Fi -> cellular handoff, and report migration_success_rate and post_migration_rtt_delta (optional)")
+	scenarioFile := flag.String("scenario", "", "Run a scripted multi-step scenario (YAML, see scenario.go for the supported subset) instead of single-GET benchmarking, with -n virtual users each running the scenario once per protocol (optional)")
+	compareFile := flag.String("compare", "", "Compare this run against a prior -o result file (which must have been saved with per-request samples) via Mann-Whitney U and a bootstrap CI on the median difference (optional)")
+	failOnRegression := flag.Bool("fail-on-regression", false, "With -compare, exit non-zero if any protocol regresses with p<0.05 and |Δ%| over -regression-threshold")
+	regressionThreshold := flag.Float64("regression-threshold", 5.0, "With -fail-on-regression, the minimum |Δ%| in median total time to count as a regression")
 	flag.Parse()
 
+	var netem *netemConfig
+	if *netemSpec != "" {
+		var err error
+		netem, err = parseNetemSpec(*netemSpec)
+		if err != nil {
+			fmt.Printf("Invalid -netem spec: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	fmt.Println("╔══════════════════════════════════════════════════════════════════╗")
 	fmt.Println("║           HTTP Protocol Benchmark Tool (H1, H2, H3)              ║")
 	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
@@ -77,16 +157,77 @@ func main() {
 	fmt.Printf("Requests per protocol: %d\n", *requests)
 	fmt.Printf("Concurrency: %d\n", *concurrency)
 	fmt.Printf("Protocols: %s\n\n", *protocols)
+	if *rate > 0 {
+		fmt.Printf("Open-model rate: %.1f req/sec\n\n", *rate)
+	}
+	if netem != nil {
+		fmt.Printf("Network emulation: %s\n\n", netem)
+	}
 
-	results := make(map[string]*BenchmarkResult)
+	var promReg *prometheusRegistry
+	if *promHTTP != "" {
+		promReg = newPrometheusRegistry()
+		servePrometheusHTTP(*promHTTP, promReg)
+	}
 
 	// Parse protocols
 	protocolList := parseProtocols(*protocols)
 
+	if *scenarioFile != "" {
+		runScenarioMode(*scenarioFile, *url, protocolList, *requests)
+		return
+	}
+
+	regressed := false
+	if *netemScenarios != "" {
+		for _, name := range splitString(*netemScenarios, ',') {
+			preset, ok := netemPreset(name)
+			if !ok {
+				fmt.Printf("Unknown netem scenario %q, skipping\n", name)
+				continue
+			}
+			fmt.Printf("\n=== Scenario: %s (%s) ===\n", preset.Name, preset)
+			if runOnce(protocolList, *url, *requests, *concurrency, *rate, *handshakeMode, preset, *migrate, promReg, *outputDir, *promFile, *compareFile, *regressionThreshold) {
+				regressed = true
+			}
+		}
+	} else {
+		regressed = runOnce(protocolList, *url, *requests, *concurrency, *rate, *handshakeMode, netem, *migrate, promReg, *outputDir, *promFile, *compareFile, *regressionThreshold)
+	}
+
+	if regressed && *failOnRegression {
+		os.Exit(1)
+	}
+}
+
+// runOnce drives one full protocol matrix (h1/h2/h3/alt-svc, as selected by -p) under a single
+// netem condition, then prints and optionally persists the results. -netem-scenarios calls this
+// once per preset; a plain run calls it once with whatever -netem (or nil) was given. netem is
+// applied only to the closed-loop and open-model (-rate) runs; the alt-svc, h3 -migrate and
+// -handshake-mode runs use the real network. It returns
+// whether -compare found a regression, so -fail-on-regression can gate the process exit code.
+func runOnce(protocolList []string, url string, requests, concurrency int, rate float64, handshakeMode string, netem *netemConfig, migrate bool, promReg *prometheusRegistry, outputDir, promFile, compareFile string, regressionThreshold float64) bool {
+	results := make(map[string]*BenchmarkResult)
+
 	for _, proto := range protocolList {
 		fmt.Printf("Testing %s...\n", proto)
-		result := runBenchmark(*url, proto, *requests, *concurrency)
+		var result *BenchmarkResult
+		switch {
+		case proto == "alt-svc":
+			result = runAltSvcBenchmark(url, requests, migrate)
+		case proto == "h3" && migrate:
+			result = runH3MigrationBenchmark(url, requests)
+		case handshakeMode != "":
+			result = runHandshakeBenchmark(url, proto, requests, handshakeMode)
+		case rate > 0:
+			result = runOpenModelBenchmark(url, proto, requests, rate, netem)
+		default:
+			result = runBenchmark(url, proto, requests, concurrency, netem)
+		}
 		results[proto] = result
+		if promReg != nil {
+			promReg.set(proto, result)
+		}
 		fmt.Printf("  Completed: %d/%d successful\n", result.SuccessfulRequests, result.TotalRequests)
 	}
 
@@ -94,14 +235,32 @@ func main() {
 	printResults(results)
 
 	// Save results to file if output directory is specified
-	if *outputDir != "" {
-		saveResults(results, *outputDir, *url, *requests, *concurrency)
+	if outputDir != "" {
+		saveResults(results, outputDir, url, requests, concurrency, netem)
 	}
+
+	if promFile != "" {
+		if err := writePrometheusFile(promFile, results); err != nil {
+			fmt.Printf("Error writing Prometheus output: %v\n", err)
+		} else {
+			fmt.Printf("\n✅ Prometheus metrics written to: %s\n", promFile)
+		}
+	}
+
+	if compareFile != "" {
+		regressed, err := runComparison(compareFile, results, regressionThreshold)
+		if err != nil {
+			fmt.Printf("Error comparing against %s: %v\n", compareFile, err)
+			return false
+		}
+		return regressed
+	}
+	return false
 }
 
 func parseProtocols(protocols string) []string {
 	var result []string
-	for _, p := range []string{"h1", "h2", "h3"} {
+	for _, p := range []string{"h1", "h2", "h3", "alt-svc"} {
 		for _, input := range splitString(protocols, ',') {
 			if input == p {
 				result = append(result, p)
@@ -131,8 +290,8 @@ func splitString(s string, sep rune) []string {
 	return result
 }
 
-func runBenchmark(url, protocol string, numRequests, concurrency int) *BenchmarkResult {
-	client := createClient(protocol)
+func runBenchmark(url, protocol string, numRequests, concurrency int, netem *netemConfig) *BenchmarkResult {
+	client := createClient(protocol, netem)
 	defer closeClient(client, protocol)
 
 	results := make([]TimingResult, 0, numRequests)
@@ -166,20 +325,27 @@ func runBenchmark(url, protocol string, numRequests, concurrency int) *Benchmark
 	return aggregateResults(protocol, results, totalDuration)
 }
 
-func createClient(protocol string) *http.Client {
+// createClient builds the http.Client used for the closed-loop and open-model benchmarks. When
+// netem is non-nil, the protocol's dialer (h1/h2) or QUIC PacketConn (h3) is wrapped so every
+// request pays the emulated latency, loss and bandwidth cap instead of the real local network.
+func createClient(protocol string, netem *netemConfig) *http.Client {
 	switch protocol {
 	case "h1":
-		return &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: false,
-				},
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 100,
-				IdleConnTimeout:     90 * time.Second,
-				TLSNextProto:        make(map[string]func(authority string, c *tls.Conn) http.RoundTripper), // Disable HTTP/2
+		transport := &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: false,
 			},
-			Timeout: 30 * time.Second,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSNextProto:        make(map[string]func(authority string, c *tls.Conn) http.RoundTripper), // Disable HTTP/2
+		}
+		if netem != nil {
+			transport.DialContext = netem.wrapDialer((&net.Dialer{}).DialContext)
+		}
+		return &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
 		}
 
 	case "h2":
@@ -189,6 +355,9 @@ func createClient(protocol string) *http.Client {
 			},
 			AllowHTTP: false,
 		}
+		if netem != nil {
+			transport.DialTLSContext = netem.wrapTLSDialer(defaultH2DialTLSContext)
+		}
 		return &http.Client{
 			Transport: transport,
 			Timeout:   30 * time.Second,
@@ -204,6 +373,9 @@ func createClient(protocol string) *http.Client {
 				KeepAlivePeriod: 10 * time.Second,
 			},
 		}
+		if netem != nil {
+			transport = netem.wrapH3Transport(transport)
+		}
 		return &http.Client{
 			Transport: transport,
 			Timeout:   30 * time.Second,
@@ -223,6 +395,13 @@ func closeClient(client *http.Client, protocol string) {
 }
 
 func makeRequest(client *http.Client, url, protocol string) TimingResult {
+	result, _ := makeRequestWithHeaders(client, url, protocol)
+	return result
+}
+
+// makeRequestWithHeaders is makeRequest plus the response header set, for callers (the alt-svc
+// protocol mode) that need to inspect a header like Alt-Svc without a second round trip.
+func makeRequestWithHeaders(client *http.Client, url, protocol string) (TimingResult, http.Header) {
 	result := TimingResult{Protocol: protocol}
 
 	var dnsStart, dnsEnd time.Time
@@ -250,6 +429,9 @@ func makeRequest(client *http.Client, url, protocol string) TimingResult {
 		},
 		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
 			tlsEnd = time.Now()
+			if err == nil {
+				result.TLSResumed = state.DidResume
+			}
 		},
 		GotFirstResponseByte: func() {
 			firstByteTime = time.Now()
@@ -260,19 +442,20 @@ func makeRequest(client *http.Client, url, protocol string) TimingResult {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		result.Error = err
-		return result
+		return result, nil
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
 		result.Error = err
 		result.TotalTime = time.Since(requestStart)
-		return result
+		return result, nil
 	}
 	defer resp.Body.Close()
 
 	// Read body to ensure full transfer
-	_, err = io.Copy(io.Discard, resp.Body)
+	n, err := io.Copy(io.Discard, resp.Body)
+	result.BytesTransferred = n
 	if err != nil {
 		result.Error = err
 	}
@@ -299,7 +482,7 @@ func makeRequest(client *http.Client, url, protocol string) TimingResult {
 		result.ContentTransfer = requestEnd.Sub(firstByteTime)
 	}
 
-	return result
+	return result, resp.Header
 }
 
 func aggregateResults(protocol string, results []TimingResult, totalDuration time.Duration) *BenchmarkResult {
@@ -311,6 +494,7 @@ func aggregateResults(protocol string, results []TimingResult, totalDuration tim
 
 	var successfulResults []TimingResult
 	var totalTimes []time.Duration
+	histograms := newProtocolHistograms()
 
 	for _, r := range results {
 		if r.Error != nil {
@@ -320,6 +504,8 @@ func aggregateResults(protocol string, results []TimingResult, totalDuration tim
 		br.SuccessfulRequests++
 		successfulResults = append(successfulResults, r)
 		totalTimes = append(totalTimes, r.TotalTime)
+		br.Samples = append(br.Samples, float64(r.TotalTime)/float64(time.Millisecond))
+		histograms.record(r)
 
 		// Accumulate for averages
 		br.AvgDNSLookup += r.DNSLookup
@@ -337,6 +523,7 @@ func aggregateResults(protocol string, results []TimingResult, totalDuration tim
 			br.MaxTotalTime = r.TotalTime
 		}
 	}
+	br.Histograms = histograms
 
 	// Calculate averages
 	if br.SuccessfulRequests > 0 {
@@ -384,16 +571,17 @@ func printResults(results map[string]*BenchmarkResult) {
 	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
 
 	// Print detailed results for each protocol
-	for _, proto := range []string{"h1", "h2", "h3"} {
+	for _, proto := range []string{"h1", "h2", "h3", "alt-svc"} {
 		r, ok := results[proto]
 		if !ok {
 			continue
 		}
 
 		protoName := map[string]string{
-			"h1": "HTTP/1.1",
-			"h2": "HTTP/2",
-			"h3": "HTTP/3 (QUIC)",
+			"h1":      "HTTP/1.1",
+			"h2":      "HTTP/2",
+			"h3":      "HTTP/3 (QUIC)",
+			"alt-svc": "Alt-Svc (H2 -> H3 upgrade)",
 		}[proto]
 
 		fmt.Printf("\n┌─────────────────────────────────────────────────────────────────┐\n")
@@ -415,6 +603,35 @@ func printResults(results map[string]*BenchmarkResult) {
 		fmt.Printf("│ %-25s %s                                      \n", "P50 (Median):", formatDuration(r.P50TotalTime))
 		fmt.Printf("│ %-25s %s                                      \n", "P95:", formatDuration(r.P95TotalTime))
 		fmt.Printf("│ %-25s %s                                      \n", "P99:", formatDuration(r.P99TotalTime))
+		if r.OpenModel {
+			fmt.Printf("├─────────────────────────────────────────────────────────────────┤\n")
+			fmt.Printf("│ Corrected for coordinated omission (rate=%.1f req/sec):           \n", r.TargetRate)
+			fmt.Printf("│ %-25s %s                                      \n", "P50 (corrected):", formatDuration(r.P50CorrectedTime))
+			fmt.Printf("│ %-25s %s                                      \n", "P95 (corrected):", formatDuration(r.P95CorrectedTime))
+			fmt.Printf("│ %-25s %s                                      \n", "P99 (corrected):", formatDuration(r.P99CorrectedTime))
+		}
+		if r.HandshakeMode != "" {
+			fmt.Printf("├─────────────────────────────────────────────────────────────────┤\n")
+			fmt.Printf("│ Handshake mode: %-51s \n", r.HandshakeMode)
+			fmt.Printf("│ First connection:  %d requests, avg %s            \n", r.FirstConnCount, formatDuration(r.AvgFirstConnTime))
+			fmt.Printf("│ Reused connection: %d requests, avg %s            \n", r.ReusedConnCount, formatDuration(r.AvgReusedConnTime))
+			if r.HandshakeMode == handshake0RTT {
+				fmt.Printf("│ 0-RTT accepted: %d/%d                                             \n", r.ZeroRTTAcceptedCount, r.TotalRequests)
+			}
+		}
+		if proto == "alt-svc" {
+			fmt.Printf("├─────────────────────────────────────────────────────────────────┤\n")
+			if r.AltSvcUpgraded {
+				fmt.Printf("│ Upgraded to H3 at request %d (%s in)                    \n", r.AltSvcUpgradeRequestIndex, formatDuration(r.AltSvcUpgradeElapsed))
+			} else {
+				fmt.Printf("│ No Alt-Svc H3 advertisement seen; stayed on H2                    \n")
+			}
+		}
+		if r.MigrationAttempted {
+			fmt.Printf("├─────────────────────────────────────────────────────────────────┤\n")
+			fmt.Printf("│ Migration success rate: %.1f%%                                     \n", r.MigrationSuccessRate*100)
+			fmt.Printf("│ Post-migration RTT delta: %s                           \n", formatDuration(r.PostMigrationRTTDelta))
+		}
 		fmt.Printf("└─────────────────────────────────────────────────────────────────┘\n")
 	}
 
@@ -506,12 +723,42 @@ func printJSONResults(results map[string]*BenchmarkResult) {
 			"p99_total_time_ms":       float64(r.P99TotalTime) / float64(time.Millisecond),
 			"requests_per_second":     r.RequestsPerSecond,
 		}
+		addOpenModelFields(jsonResults[proto].(map[string]interface{}), r)
+		addAltSvcAndMigrationFields(jsonResults[proto].(map[string]interface{}), r)
 	}
 
 	jsonBytes, _ := json.MarshalIndent(jsonResults, "", "  ")
 	fmt.Println(string(jsonBytes))
 }
 
+// addOpenModelFields adds the coordinated-omission-corrected percentiles to a result's JSON
+// representation when the benchmark was run in open-model (-rate) mode; it's a no-op otherwise.
+func addOpenModelFields(m map[string]interface{}, r *BenchmarkResult) {
+	if !r.OpenModel {
+		return
+	}
+	m["open_model"] = true
+	m["target_rate_rps"] = r.TargetRate
+	m["p50_corrected_time_ms"] = float64(r.P50CorrectedTime) / float64(time.Millisecond)
+	m["p95_corrected_time_ms"] = float64(r.P95CorrectedTime) / float64(time.Millisecond)
+	m["p99_corrected_time_ms"] = float64(r.P99CorrectedTime) / float64(time.Millisecond)
+}
+
+// addAltSvcAndMigrationFields adds the alt-svc upgrade point and/or -migrate metrics to a
+// result's JSON representation; each group is a no-op unless that mode actually ran.
+func addAltSvcAndMigrationFields(m map[string]interface{}, r *BenchmarkResult) {
+	if r.Protocol == "alt-svc" {
+		m["alt_svc_upgraded"] = r.AltSvcUpgraded
+		m["alt_svc_upgrade_request_index"] = r.AltSvcUpgradeRequestIndex
+		m["alt_svc_upgrade_elapsed_ms"] = float64(r.AltSvcUpgradeElapsed) / float64(time.Millisecond)
+	}
+	if r.MigrationAttempted {
+		m["migration_attempted"] = true
+		m["migration_success_rate"] = r.MigrationSuccessRate
+		m["post_migration_rtt_delta_ms"] = float64(r.PostMigrationRTTDelta) / float64(time.Millisecond)
+	}
+}
+
 func formatDuration(d time.Duration) string {
 	if d == 0 {
 		return "0ms"
@@ -551,7 +798,7 @@ func init() {
 	net.DefaultResolver.PreferGo = true
 }
 
-func saveResults(results map[string]*BenchmarkResult, outputDir, url string, requests, concurrency int) {
+func saveResults(results map[string]*BenchmarkResult, outputDir, url string, requests, concurrency int, netem *netemConfig) {
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		fmt.Printf("Error creating output directory: %v\n", err)
@@ -574,6 +821,16 @@ func saveResults(results map[string]*BenchmarkResult, outputDir, url string, req
 		"results": map[string]interface{}{},
 	}
 
+	if netem != nil {
+		output["metadata"].(map[string]interface{})["netem"] = map[string]interface{}{
+			"name":          netem.Name,
+			"rtt_ms":        float64(netem.RTT) / float64(time.Millisecond),
+			"jitter_ms":     float64(netem.Jitter) / float64(time.Millisecond),
+			"loss_percent":  netem.LossPercent,
+			"bandwidth_bps": netem.BandwidthBitsPerSec,
+		}
+	}
+
 	resultsMap := output["results"].(map[string]interface{})
 	for proto, r := range results {
 		resultsMap[proto] = map[string]interface{}{
@@ -593,7 +850,10 @@ func saveResults(results map[string]*BenchmarkResult, outputDir, url string, req
 			"p95_total_time_ms":       float64(r.P95TotalTime) / float64(time.Millisecond),
 			"p99_total_time_ms":       float64(r.P99TotalTime) / float64(time.Millisecond),
 			"requests_per_second":     r.RequestsPerSecond,
+			"samples_ms":              r.Samples,
 		}
+		addOpenModelFields(resultsMap[proto].(map[string]interface{}), r)
+		addAltSvcAndMigrationFields(resultsMap[proto].(map[string]interface{}), r)
 	}
 
 	jsonBytes, err := json.MarshalIndent(output, "", "  ")
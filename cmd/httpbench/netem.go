@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// netemConfig describes the network conditions to emulate for a benchmark run, either parsed
+// from -netem or looked up from netemPresets for -netem-scenarios. HTTP/3's advertised advantages
+// (no head-of-line blocking, 0-RTT) only actually show up once the network has real RTT and loss,
+// so this wraps the transport's dialer (h1/h2) or the QUIC PacketConn (h3) with an in-process
+// emulator instead of requiring a real lossy network to demonstrate the difference.
+type netemConfig struct {
+	Name                string // preset name for -netem-scenarios; the raw spec for -netem
+	RTT                 time.Duration
+	Jitter              time.Duration
+	LossPercent         float64
+	BandwidthBitsPerSec float64
+}
+
+func (c *netemConfig) String() string {
+	return fmt.Sprintf("rtt=%s jitter=%s loss=%.2f%% bandwidth=%s",
+		c.RTT, c.Jitter, c.LossPercent, formatBandwidth(c.BandwidthBitsPerSec))
+}
+
+func formatBandwidth(bps float64) string {
+	switch {
+	case bps <= 0:
+		return "unlimited"
+	case bps >= 1e9:
+		return fmt.Sprintf("%.2fGbit", bps/1e9)
+	case bps >= 1e6:
+		return fmt.Sprintf("%.2fMbit", bps/1e6)
+	case bps >= 1e3:
+		return fmt.Sprintf("%.2fKbit", bps/1e3)
+	default:
+		return fmt.Sprintf("%.0fbit", bps)
+	}
+}
+
+// netemPresets back -netem-scenarios, roughly modeled on real-world link profiles so the full
+// benchmark matrix can be run against each in one invocation.
+var netemPresets = []*netemConfig{
+	{Name: "LAN", RTT: time.Millisecond, Jitter: 0, LossPercent: 0, BandwidthBitsPerSec: 1e9},
+	{Name: "4G", RTT: 50 * time.Millisecond, Jitter: 10 * time.Millisecond, LossPercent: 0.1, BandwidthBitsPerSec: 20e6},
+	{Name: "3G", RTT: 150 * time.Millisecond, Jitter: 30 * time.Millisecond, LossPercent: 1, BandwidthBitsPerSec: 1.5e6},
+	{Name: "satellite", RTT: 600 * time.Millisecond, Jitter: 20 * time.Millisecond, LossPercent: 0.5, BandwidthBitsPerSec: 10e6},
+	{Name: "lossy-wifi", RTT: 20 * time.Millisecond, Jitter: 15 * time.Millisecond, LossPercent: 5, BandwidthBitsPerSec: 30e6},
+}
+
+func netemPreset(name string) (*netemConfig, bool) {
+	for _, p := range netemPresets {
+		if strings.EqualFold(p.Name, name) {
+			cp := *p
+			return &cp, true
+		}
+	}
+	return nil, false
+}
+
+// parseNetemSpec parses a comma-separated "key=value" spec such as
+// "rtt=100ms,jitter=10ms,loss=1%,bandwidth=10Mbit" into a netemConfig. Any term may be omitted.
+func parseNetemSpec(spec string) (*netemConfig, error) {
+	cfg := &netemConfig{Name: spec}
+	for _, term := range splitString(spec, ',') {
+		kv := strings.SplitN(term, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid netem term %q: expected key=value", term)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "rtt":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid netem rtt %q: %w", value, err)
+			}
+			cfg.RTT = d
+		case "jitter":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid netem jitter %q: %w", value, err)
+			}
+			cfg.Jitter = d
+		case "loss":
+			pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid netem loss %q: %w", value, err)
+			}
+			cfg.LossPercent = pct
+		case "bandwidth":
+			bps, err := parseBandwidth(value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.BandwidthBitsPerSec = bps
+		default:
+			return nil, fmt.Errorf("unknown netem term %q", key)
+		}
+	}
+	return cfg, nil
+}
+
+func parseBandwidth(value string) (float64, error) {
+	lower := strings.ToLower(value)
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(lower, "gbit"):
+		multiplier = 1e9
+		lower = strings.TrimSuffix(lower, "gbit")
+	case strings.HasSuffix(lower, "mbit"):
+		multiplier = 1e6
+		lower = strings.TrimSuffix(lower, "mbit")
+	case strings.HasSuffix(lower, "kbit"):
+		multiplier = 1e3
+		lower = strings.TrimSuffix(lower, "kbit")
+	case strings.HasSuffix(lower, "bit"):
+		lower = strings.TrimSuffix(lower, "bit")
+	default:
+		return 0, fmt.Errorf("invalid netem bandwidth %q: expected a bit/kbit/mbit/gbit suffix", value)
+	}
+	n, err := strconv.ParseFloat(lower, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid netem bandwidth %q: %w", value, err)
+	}
+	return n * multiplier, nil
+}
+
+func (c *netemConfig) delay() time.Duration {
+	d := c.RTT / 2
+	if c.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(c.Jitter)*2)) - c.Jitter
+		if d < 0 {
+			d = 0
+		}
+	}
+	return d
+}
+
+func (c *netemConfig) shouldDrop() bool {
+	return c.LossPercent > 0 && rand.Float64()*100 < c.LossPercent
+}
+
+// tokenBucket throttles throughput to rateBitsPerSec. Each wrapDialer, wrapTLSDialer or
+// wrapH3Transport call creates one bucket shared by every Read/Write of the connections it
+// produces, so one client models one link's capacity rather than a per-connection allowance.
+type tokenBucket struct {
+	mu             sync.Mutex
+	rateBitsPerSec float64
+	capacityBits   float64
+	tokensBits     float64
+	last           time.Time
+}
+
+func newTokenBucket(rateBitsPerSec float64) *tokenBucket {
+	return &tokenBucket{
+		rateBitsPerSec: rateBitsPerSec,
+		capacityBits:   rateBitsPerSec, // one second of burst
+		tokensBits:     rateBitsPerSec,
+		last:           time.Now(),
+	}
+}
+
+// take blocks until n bytes' worth of tokens are available, then consumes them. A nil bucket or
+// an unlimited (<=0) rate is a no-op.
+func (b *tokenBucket) take(n int) {
+	if b == nil || b.rateBitsPerSec <= 0 {
+		return
+	}
+	needed := float64(n) * 8
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokensBits = math.Min(b.capacityBits, b.tokensBits+now.Sub(b.last).Seconds()*b.rateBitsPerSec)
+		b.last = now
+		if b.tokensBits >= needed {
+			b.tokensBits -= needed
+			b.mu.Unlock()
+			return
+		}
+		wait := time.Duration((needed - b.tokensBits) / b.rateBitsPerSec * float64(time.Second))
+		b.tokensBits = 0
+		b.mu.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+// netemConn wraps a net.Conn to add latency/jitter and bandwidth throttling to every Read/Write,
+// for the H1/H2 dialer. It does not drop bytes: TCP is a reliable ordered byte stream, so dropping
+// bytes below net.Conn would just corrupt it rather than trigger retransmission the way real
+// packet loss does — that only makes sense at the datagram level, which is why loss is only
+// simulated for H3's PacketConn below.
+type netemConn struct {
+	net.Conn
+	cfg *netemConfig
+	tb  *tokenBucket
+}
+
+func newNetemConn(conn net.Conn, cfg *netemConfig, tb *tokenBucket) net.Conn {
+	return &netemConn{Conn: conn, cfg: cfg, tb: tb}
+}
+
+func (c *netemConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.tb.take(n)
+		time.Sleep(c.cfg.delay())
+	}
+	return n, err
+}
+
+func (c *netemConn) Write(p []byte) (int, error) {
+	c.tb.take(len(p))
+	time.Sleep(c.cfg.delay())
+	return c.Conn.Write(p)
+}
+
+// netemPacketConn wraps a UDP net.PacketConn so delay, jitter, loss and bandwidth throttling are
+// applied per-datagram — QUIC's actual unit of transmission, and the level at which H3's
+// independent per-stream loss recovery genuinely diverges from TCP's single ordered byte stream.
+type netemPacketConn struct {
+	net.PacketConn
+	cfg *netemConfig
+	tb  *tokenBucket
+}
+
+func newNetemPacketConn(pc net.PacketConn, cfg *netemConfig, tb *tokenBucket) net.PacketConn {
+	return &netemPacketConn{PacketConn: pc, cfg: cfg, tb: tb}
+}
+
+func (c *netemPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil || n == 0 {
+			return n, addr, err
+		}
+		if c.cfg.shouldDrop() {
+			continue
+		}
+		c.tb.take(n)
+		time.Sleep(c.cfg.delay())
+		return n, addr, err
+	}
+}
+
+func (c *netemPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if c.cfg.shouldDrop() {
+		return len(p), nil // pretend it was sent; the peer never sees the datagram
+	}
+	c.tb.take(len(p))
+	time.Sleep(c.cfg.delay())
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+// wrapDialer returns a DialContext hook that dials normally via dial, then wraps the resulting
+// net.Conn in netem, for use as an http.Transport.DialContext replacement.
+func (cfg *netemConfig) wrapDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	tb := newTokenBucket(cfg.BandwidthBitsPerSec)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return newNetemConn(conn, cfg, tb), nil
+	}
+}
+
+// wrapTLSDialer is the http2.Transport.DialTLSContext equivalent of wrapDialer.
+func (cfg *netemConfig) wrapTLSDialer(dial func(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error)) func(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
+	tb := newTokenBucket(cfg.BandwidthBitsPerSec)
+	return func(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr, tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+		return newNetemConn(conn, cfg, tb), nil
+	}
+}
+
+// defaultH2DialTLSContext is the dial http2.Transport would use internally; wrapTLSDialer wraps
+// this one when no other DialTLSContext has been set.
+func defaultH2DialTLSContext(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
+	d := &tls.Dialer{Config: tlsCfg}
+	return d.DialContext(ctx, network, addr)
+}
+
+// wrapH3Transport rebinds transport onto a UDP socket wrapped in netemPacketConn, so per-datagram
+// delay/jitter/loss/bandwidth apply to the actual QUIC packets it sends and receives.
+func (cfg *netemConfig) wrapH3Transport(transport *http3.Transport) *http3.Transport {
+	pc, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		fmt.Printf("netem: failed to open UDP socket for H3 emulation, running unthrottled: %v\n", err)
+		return transport
+	}
+	tb := newTokenBucket(cfg.BandwidthBitsPerSec)
+	qt := &quic.Transport{Conn: newNetemPacketConn(pc, cfg, tb)}
+
+	transport.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, quicCfg *quic.Config) (quic.EarlyConnection, error) {
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return qt.DialEarly(ctx, udpAddr, tlsCfg, quicCfg)
+	}
+	return transport
+}
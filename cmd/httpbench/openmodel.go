@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statsInterval controls how often the running-stats line is printed during an open-model run.
+const statsInterval = 10 * time.Second
+
+// runOpenModelBenchmark schedules requests on a fixed-interval arrival process at `rate`
+// requests/sec, independent of in-flight completions. This avoids the coordinated-omission
+// problem of the closed-loop runBenchmark, where a slow request delays every request queued
+// behind it and so hides tail latency under load.
+func runOpenModelBenchmark(url, protocol string, numRequests int, rate float64, netem *netemConfig) *BenchmarkResult {
+	client := createClient(protocol, netem)
+	defer closeClient(client, protocol)
+
+	results := make([]TimingResult, numRequests)
+	var wg sync.WaitGroup
+
+	var sent, received int64
+	var bytesTransferred int64
+	runningLatencies := newRunningLatencyTracker()
+
+	stopStats := make(chan struct{})
+	go printRunningStats(&sent, &received, &bytesTransferred, runningLatencies, stopStats)
+
+	interval := time.Duration(float64(time.Second) / rate)
+	start := time.Now()
+
+	for i := 0; i < numRequests; i++ {
+		intended := start.Add(time.Duration(i) * interval)
+		if d := time.Until(intended); d > 0 {
+			time.Sleep(d)
+		}
+
+		wg.Add(1)
+		atomic.AddInt64(&sent, 1)
+		go func(reqNum int, intendedStart time.Time) {
+			defer wg.Done()
+
+			actualStart := time.Now()
+			result := makeRequest(client, url, protocol)
+			result.IntendedStart = intendedStart
+			result.ActualStart = actualStart
+			result.CorrectedTotalTime = time.Since(intendedStart)
+
+			atomic.AddInt64(&received, 1)
+			if result.Error == nil {
+				runningLatencies.record(result.TotalTime)
+				atomic.AddInt64(&bytesTransferred, result.BytesTransferred)
+			}
+			results[reqNum] = result
+		}(i, intended)
+	}
+
+	wg.Wait()
+	close(stopStats)
+	totalDuration := time.Since(start)
+
+	br := aggregateResults(protocol, results, totalDuration)
+	br.OpenModel = true
+	br.TargetRate = rate
+	br.P50CorrectedTime, br.P95CorrectedTime, br.P99CorrectedTime = correctedPercentiles(results)
+	return br
+}
+
+// correctedPercentiles computes P50/P95/P99 of CorrectedTotalTime (completion - intended send
+// time) across successful requests, which is the metric that actually exposes queueing delay
+// under an open-model load.
+func correctedPercentiles(results []TimingResult) (p50, p95, p99 time.Duration) {
+	corrected := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if r.Error == nil {
+			corrected = append(corrected, r.CorrectedTotalTime)
+		}
+	}
+	if len(corrected) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(corrected, func(i, j int) bool { return corrected[i] < corrected[j] })
+	return percentile(corrected, 50), percentile(corrected, 95), percentile(corrected, 99)
+}
+
+// runningLatencyTracker keeps a bounded, mutex-guarded window of recent latencies so the
+// periodic stats line can report a running p99 without retaining every sample forever.
+type runningLatencyTracker struct {
+	mu      sync.Mutex
+	samples []time.Duration
+}
+
+const runningLatencyWindow = 10000
+
+func newRunningLatencyTracker() *runningLatencyTracker {
+	return &runningLatencyTracker{samples: make([]time.Duration, 0, runningLatencyWindow)}
+}
+
+func (t *runningLatencyTracker) record(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) >= runningLatencyWindow {
+		t.samples = t.samples[1:]
+	}
+	t.samples = append(t.samples, d)
+}
+
+func (t *runningLatencyTracker) p99() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), t.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return percentile(sorted, 99)
+}
+
+// printRunningStats prints a single-line progress update every statsInterval until stop is
+// closed, so long open-model runs are observable instead of silent until completion.
+func printRunningStats(sent, received, bytesTransferred *int64, latencies *runningLatencyTracker, stop <-chan struct{}) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Printf("  [stats] sent=%d received=%d bytes=%d running_p99=%s\n",
+				atomic.LoadInt64(sent), atomic.LoadInt64(received), atomic.LoadInt64(bytesTransferred), formatDuration(latencies.p99()))
+		case <-stop:
+			return
+		}
+	}
+}
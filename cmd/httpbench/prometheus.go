@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// protocolHistograms holds the per-metric latency histograms for one protocol's run, recorded
+// directly from each successful TimingResult in aggregateResults.
+type protocolHistograms struct {
+	Total *Histogram
+	DNS   *Histogram
+	TCP   *Histogram
+	TLS   *Histogram
+	TTFB  *Histogram
+}
+
+func newProtocolHistograms() *protocolHistograms {
+	return &protocolHistograms{
+		Total: NewHistogram(),
+		DNS:   NewHistogram(),
+		TCP:   NewHistogram(),
+		TLS:   NewHistogram(),
+		TTFB:  NewHistogram(),
+	}
+}
+
+func (h *protocolHistograms) record(r TimingResult) {
+	h.Total.Record(r.TotalTime)
+	h.DNS.Record(r.DNSLookup)
+	h.TCP.Record(r.TCPConnect)
+	h.TLS.Record(r.TLSHandshake)
+	h.TTFB.Record(r.TimeToFirstByte)
+}
+
+// prometheusRegistry holds the latest BenchmarkResult per protocol behind a mutex, so -promhttp
+// can serve metrics for protocols that have already finished while a later protocol is still
+// running.
+type prometheusRegistry struct {
+	mu      sync.Mutex
+	results map[string]*BenchmarkResult
+}
+
+func newPrometheusRegistry() *prometheusRegistry {
+	return &prometheusRegistry{results: make(map[string]*BenchmarkResult)}
+}
+
+func (p *prometheusRegistry) set(protocol string, r *BenchmarkResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results[protocol] = r
+}
+
+func (p *prometheusRegistry) render() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP http_bench_requests_total Total requests issued per protocol.\n")
+	b.WriteString("# TYPE http_bench_requests_total counter\n")
+	for proto, r := range p.results {
+		fmt.Fprintf(&b, "http_bench_requests_total{protocol=%q} %d\n", proto, r.TotalRequests)
+	}
+
+	b.WriteString("# HELP http_bench_request_errors_total Failed requests per protocol.\n")
+	b.WriteString("# TYPE http_bench_request_errors_total counter\n")
+	for proto, r := range p.results {
+		fmt.Fprintf(&b, "http_bench_request_errors_total{protocol=%q} %d\n", proto, r.FailedRequests)
+	}
+
+	writeHistogram := func(metric string, get func(*protocolHistograms) *Histogram) {
+		fmt.Fprintf(&b, "# HELP %s %s latency distribution.\n", metric, metric)
+		fmt.Fprintf(&b, "# TYPE %s histogram\n", metric)
+		for proto, r := range p.results {
+			if r.Histograms == nil {
+				continue
+			}
+			get(r.Histograms).writePrometheus(&b, metric, fmt.Sprintf("protocol=%q", proto))
+		}
+	}
+
+	writeHistogram("http_bench_request_duration_seconds", func(h *protocolHistograms) *Histogram { return h.Total })
+	writeHistogram("http_bench_dns_duration_seconds", func(h *protocolHistograms) *Histogram { return h.DNS })
+	writeHistogram("http_bench_tcp_duration_seconds", func(h *protocolHistograms) *Histogram { return h.TCP })
+	writeHistogram("http_bench_tls_duration_seconds", func(h *protocolHistograms) *Histogram { return h.TLS })
+	writeHistogram("http_bench_ttfb_duration_seconds", func(h *protocolHistograms) *Histogram { return h.TTFB })
+
+	return b.String()
+}
+
+// writePrometheusFile writes the current set of results to path in Prometheus exposition format.
+func writePrometheusFile(path string, results map[string]*BenchmarkResult) error {
+	reg := newPrometheusRegistry()
+	for proto, r := range results {
+		reg.set(proto, r)
+	}
+	return os.WriteFile(path, []byte(reg.render()), 0644)
+}
+
+// servePrometheusHTTP starts a background HTTP server exposing /metrics from reg, so a live
+// benchmark run can be scraped by Prometheus/Grafana as it progresses.
+func servePrometheusHTTP(addr string, reg *prometheusRegistry) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, reg.render())
+	})
+
+	go func() {
+		log.Printf("Serving live Prometheus metrics on http://%s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("promhttp server error: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// scenarioStep is one named request in a -scenario file. Path, Body and each Headers value may
+// reference an earlier step's parsed JSON response via {{.stepName.field}}. Parallel holds the
+// sub-steps of a `parallel:` group, which are issued concurrently on the same per-user connection
+// to measure H2/H3 multiplexing; a step with Parallel set otherwise carries no fields of its own.
+type scenarioStep struct {
+	Name     string
+	Method   string
+	Path     string
+	Headers  map[string]string
+	Body     string
+	Stream   bool
+	Parallel []scenarioStep
+}
+
+// scenarioDef is a parsed -scenario file: the step sequence a single virtual user runs.
+type scenarioDef struct {
+	Steps []scenarioStep
+}
+
+// allStepsInOrder flattens parallel groups into their member steps, in definition order, for
+// reporting (the results table shows one row per named step regardless of grouping).
+func (s *scenarioDef) allStepsInOrder() []scenarioStep {
+	var out []scenarioStep
+	for _, step := range s.Steps {
+		if len(step.Parallel) > 0 {
+			out = append(out, step.Parallel...)
+			continue
+		}
+		out = append(out, step)
+	}
+	return out
+}
+
+func (step *scenarioStep) applyField(field string) error {
+	key, value, ok := strings.Cut(field, ":")
+	if !ok {
+		return fmt.Errorf("malformed scenario field %q", field)
+	}
+	key = strings.TrimSpace(key)
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	switch key {
+	case "name":
+		step.Name = value
+	case "method":
+		step.Method = value
+	case "path":
+		step.Path = value
+	case "body":
+		step.Body = value
+	case "stream":
+		step.Stream = value == "true"
+	default:
+		return fmt.Errorf("unknown scenario step field %q", key)
+	}
+	return nil
+}
+
+// yamlLine is one non-blank, non-comment line of a scenario file, with its leading-space
+// indentation measured and its content otherwise untouched.
+type yamlLine struct {
+	indent int
+	text   string
+}
+
+func tokenizeYAMLLines(raw []string) []yamlLine {
+	var out []yamlLine
+	for _, l := range raw {
+		withoutTrailingWS := strings.TrimRight(l, " \t\r")
+		trimmed := strings.TrimLeft(withoutTrailingWS, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		out = append(out, yamlLine{indent: len(withoutTrailingWS) - len(trimmed), text: trimmed})
+	}
+	return out
+}
+
+// lineCursor walks the tokenized lines of a scenario file for the recursive-descent parser below.
+type lineCursor struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (c *lineCursor) peek() (yamlLine, bool) {
+	if c.pos >= len(c.lines) {
+		return yamlLine{}, false
+	}
+	return c.lines[c.pos], true
+}
+
+func (c *lineCursor) next() (yamlLine, bool) {
+	l, ok := c.peek()
+	if ok {
+		c.pos++
+	}
+	return l, ok
+}
+
+// parseScenarioFile parses the deliberately small YAML subset -scenario needs: a top-level
+// "steps:" list, each item a step with name/method/path/body/stream fields, an optional nested
+// "headers:" map, and an optional nested "parallel:" list of sub-steps. Pulling in a full YAML
+// library isn't warranted for a format this constrained.
+func parseScenarioFile(path string) (*scenarioDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &lineCursor{lines: tokenizeYAMLLines(strings.Split(string(data), "\n"))}
+
+	first, ok := c.next()
+	if !ok || first.text != "steps:" {
+		return nil, fmt.Errorf("scenario file must start with a top-level \"steps:\" list")
+	}
+	steps, err := parseStepList(c)
+	if err != nil {
+		return nil, err
+	}
+	return &scenarioDef{Steps: steps}, nil
+}
+
+// parseStepList parses a YAML-style list of step blocks. Each item starts with "- " and its
+// indentation anchors the block: subsequent "key: value" lines indented further belong to that
+// item, until a line at or below the list's own indentation ends it.
+func parseStepList(c *lineCursor) ([]scenarioStep, error) {
+	first, ok := c.peek()
+	if !ok || !strings.HasPrefix(first.text, "- ") {
+		return nil, fmt.Errorf("expected a scenario list item (\"- ...\")")
+	}
+	listIndent := first.indent
+
+	var steps []scenarioStep
+	for {
+		line, ok := c.peek()
+		if !ok || line.indent != listIndent || !strings.HasPrefix(line.text, "- ") {
+			break
+		}
+		c.next()
+		step, err := parseStepBody(c, listIndent, strings.TrimPrefix(line.text, "- "))
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// parseStepBody parses one step's fields. firstField is the text following the list item's
+// leading "- " on its own line; the rest are later lines indented further than listIndent.
+func parseStepBody(c *lineCursor, listIndent int, firstField string) (scenarioStep, error) {
+	var step scenarioStep
+	step.Headers = make(map[string]string)
+
+	dispatch := func(text string, indent int) error {
+		switch text {
+		case "headers:":
+			headers, err := parseMap(c, indent)
+			if err != nil {
+				return err
+			}
+			step.Headers = headers
+		case "parallel:":
+			sub, err := parseStepList(c)
+			if err != nil {
+				return err
+			}
+			step.Parallel = sub
+		default:
+			return step.applyField(text)
+		}
+		return nil
+	}
+
+	if firstField != "" {
+		if err := dispatch(firstField, listIndent+len("- ")); err != nil {
+			return step, err
+		}
+	}
+
+	for {
+		line, ok := c.peek()
+		if !ok || line.indent <= listIndent {
+			break
+		}
+		c.next()
+		if err := dispatch(line.text, line.indent); err != nil {
+			return step, err
+		}
+	}
+	return step, nil
+}
+
+// parseMap parses "key: value" lines indented deeper than parentIndent, until a dedent ends them.
+func parseMap(c *lineCursor, parentIndent int) (map[string]string, error) {
+	m := make(map[string]string)
+	for {
+		line, ok := c.peek()
+		if !ok || line.indent <= parentIndent {
+			break
+		}
+		c.next()
+		key, value, ok := strings.Cut(line.text, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed scenario map entry %q", line.text)
+		}
+		m[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return m, nil
+}
+
+// templateRefRe matches {{.stepName.field.nested}} references into an earlier step's parsed JSON
+// response.
+var templateRefRe = regexp.MustCompile(`\{\{\.([a-zA-Z0-9_]+)((?:\.[a-zA-Z0-9_]+)*)\}\}`)
+
+// renderTemplate substitutes every {{.step.field}} reference in s with the referenced field from
+// responses, a value json.Unmarshal produced for that step. An unresolvable reference (step
+// hasn't run yet, or the field doesn't exist) is left in place rather than silently blanked out,
+// so a scenario author can immediately see what went wrong.
+func renderTemplate(s string, responses map[string]interface{}) string {
+	return templateRefRe.ReplaceAllStringFunc(s, func(match string) string {
+		groups := templateRefRe.FindStringSubmatch(match)
+		value, ok := extractJSONPath(responses[groups[1]], strings.TrimPrefix(groups[2], "."))
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf("%v", value)
+	})
+}
+
+// extractJSONPath walks a dot-separated path (e.g. "login.token") into root, a value produced by
+// json.Unmarshal into interface{} (so nested objects are map[string]interface{}).
+func extractJSONPath(root interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return root, root != nil
+	}
+	cur := root
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func stepURL(base *neturl.URL, path string) string {
+	u := *base
+	u.Path = path
+	u.RawQuery = ""
+	return u.String()
+}
+
+// executeStep issues one scenario step's request over client and returns its timing (in the same
+// shape as the core benchmark's makeRequest), its parsed JSON response body (nil if the body
+// isn't JSON, so later steps can't template off it) and, for stream: true steps, the inter-arrival
+// latency between consecutive body reads.
+func executeStep(client *http.Client, protocol string, baseURL *neturl.URL, step scenarioStep, responses map[string]interface{}, responsesMu *sync.Mutex) (TimingResult, interface{}, []time.Duration) {
+	responsesMu.Lock()
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	url := stepURL(baseURL, renderTemplate(step.Path, responses))
+	body := renderTemplate(step.Body, responses)
+	headers := make(map[string]string, len(step.Headers))
+	for k, v := range step.Headers {
+		headers[k] = renderTemplate(v, responses)
+	}
+	responsesMu.Unlock()
+
+	result := TimingResult{Protocol: protocol}
+	requestStart := time.Now()
+
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, bodyReader)
+	if err != nil {
+		result.Error = err
+		return result, nil, nil
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err
+		result.TotalTime = time.Since(requestStart)
+		return result, nil, nil
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	var chunkLatencies []time.Duration
+	if step.Stream {
+		chunkLatencies = readStreamed(resp.Body, &buf)
+	} else {
+		io.Copy(&buf, resp.Body)
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.BytesTransferred = int64(buf.Len())
+	result.TotalTime = time.Since(requestStart)
+
+	var parsed interface{}
+	_ = json.Unmarshal(buf.Bytes(), &parsed)
+
+	return result, parsed, chunkLatencies
+}
+
+// readStreamed copies body into dst while recording the latency between consecutive reads — the
+// per-chunk inter-arrival latency stream: true steps exist to measure.
+func readStreamed(body io.Reader, dst *bytes.Buffer) []time.Duration {
+	var latencies []time.Duration
+	buf := make([]byte, 4096)
+	last := time.Now()
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			now := time.Now()
+			latencies = append(latencies, now.Sub(last))
+			last = now
+			dst.Write(buf[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+	return latencies
+}
+
+// scenarioReport is one protocol's results from running a scenario across numUsers virtual
+// users: per-step aggregated timings (so the table shows a login/list/detail row per step) plus
+// the scenario-level "max concurrent streams observed" derived from goroutine instrumentation
+// around parallel groups.
+type scenarioReport struct {
+	Protocol             string
+	StepOrder            []string
+	StepResults          map[string]*BenchmarkResult
+	StepAvgChunkLatency  map[string]time.Duration
+	MaxConcurrentStreams int
+}
+
+// runScenarioForProtocol runs def numUsers times against protocol, one goroutine per virtual
+// user, each holding its own client for the user's whole step sequence. Steps within a
+// `parallel:` group are issued concurrently on that client: under h2/h3 they share the user's one
+// connection as multiplexed streams, while the h1 client opens another connection for each
+// overlapping step. maxConcurrentStreams is the high-water mark of one user's steps in flight at
+// once, tracked per user with a CAS loop (since a user's own parallel group can run several of
+// its sub-goroutines at once) and then reduced to the single largest per-user high-water mark
+// across all users. It counts in-flight requests on a client, not streams on one connection, so
+// a parallel group reaches the same value under h1, h2 and h3. Tracking it globally across every
+// user's unrelated, unsynchronized clients would instead count users that merely overlapped in
+// time.
+func runScenarioForProtocol(def *scenarioDef, baseURL *neturl.URL, protocol string, numUsers int) *scenarioReport {
+	stepTimings := make(map[string][]TimingResult)
+	stepChunkLatencies := make(map[string][]time.Duration)
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+
+	var maxStreams int64
+
+	trackStream := func(active, userMax *int64, delta int64) {
+		cur := atomic.AddInt64(active, delta)
+		for {
+			old := atomic.LoadInt64(userMax)
+			if cur <= old || atomic.CompareAndSwapInt64(userMax, old, cur) {
+				return
+			}
+		}
+	}
+
+	recordUserMax := func(userMax int64) {
+		for {
+			old := atomic.LoadInt64(&maxStreams)
+			if userMax <= old || atomic.CompareAndSwapInt64(&maxStreams, old, userMax) {
+				return
+			}
+		}
+	}
+
+	runStep := func(client *http.Client, active, userMax *int64, step scenarioStep, responses map[string]interface{}, responsesMu *sync.Mutex) {
+		trackStream(active, userMax, 1)
+		timing, parsed, chunkLatencies := executeStep(client, protocol, baseURL, step, responses, responsesMu)
+		trackStream(active, userMax, -1)
+
+		responsesMu.Lock()
+		responses[step.Name] = parsed
+		responsesMu.Unlock()
+
+		resultsMu.Lock()
+		stepTimings[step.Name] = append(stepTimings[step.Name], timing)
+		stepChunkLatencies[step.Name] = append(stepChunkLatencies[step.Name], chunkLatencies...)
+		resultsMu.Unlock()
+	}
+
+	for u := 0; u < numUsers; u++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := createClient(protocol, nil)
+			defer closeClient(client, protocol)
+
+			responses := make(map[string]interface{})
+			var responsesMu sync.Mutex
+			var active, userMax int64
+
+			for _, step := range def.Steps {
+				if len(step.Parallel) > 0 {
+					var groupWG sync.WaitGroup
+					for _, sub := range step.Parallel {
+						groupWG.Add(1)
+						go func(s scenarioStep) {
+							defer groupWG.Done()
+							runStep(client, &active, &userMax, s, responses, &responsesMu)
+						}(sub)
+					}
+					groupWG.Wait()
+					continue
+				}
+				runStep(client, &active, &userMax, step, responses, &responsesMu)
+			}
+
+			recordUserMax(userMax)
+		}()
+	}
+	wg.Wait()
+
+	report := &scenarioReport{
+		Protocol:             protocol,
+		StepResults:          make(map[string]*BenchmarkResult),
+		StepAvgChunkLatency:  make(map[string]time.Duration),
+		MaxConcurrentStreams: int(atomic.LoadInt64(&maxStreams)),
+	}
+	for _, step := range def.allStepsInOrder() {
+		report.StepOrder = append(report.StepOrder, step.Name)
+		report.StepResults[step.Name] = aggregateResults(protocol, stepTimings[step.Name], 0)
+
+		latencies := stepChunkLatencies[step.Name]
+		if len(latencies) == 0 {
+			continue
+		}
+		var total time.Duration
+		for _, d := range latencies {
+			total += d
+		}
+		report.StepAvgChunkLatency[step.Name] = total / time.Duration(len(latencies))
+	}
+	return report
+}
+
+// runScenarioMode parses scenarioPath and runs it against each protocol in protocolList with
+// numUsers virtual users per protocol, printing a per-step results table for each.
+func runScenarioMode(scenarioPath, rawBaseURL string, protocolList []string, numUsers int) {
+	def, err := parseScenarioFile(scenarioPath)
+	if err != nil {
+		fmt.Printf("Error parsing scenario file: %v\n", err)
+		os.Exit(1)
+	}
+	baseURL, err := neturl.Parse(rawBaseURL)
+	if err != nil {
+		fmt.Printf("Error parsing -url: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, proto := range protocolList {
+		fmt.Printf("Running scenario %q against %s (%d virtual users)...\n", scenarioPath, proto, numUsers)
+		report := runScenarioForProtocol(def, baseURL, proto, numUsers)
+		printScenarioReport(report)
+	}
+}
+
+func printScenarioReport(r *scenarioReport) {
+	fmt.Printf("\n┌─────────────────────────────────────────────────────────────────┐\n")
+	fmt.Printf("│ Scenario results: %-49s \n", r.Protocol)
+	fmt.Printf("├─────────────────────────────────────────────────────────────────┤\n")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Step\tSuccess\tAvg Total\tP95\tAvg Chunk Latency")
+	for _, name := range r.StepOrder {
+		br := r.StepResults[name]
+		chunkLatency := "N/A"
+		if d, ok := r.StepAvgChunkLatency[name]; ok {
+			chunkLatency = formatDuration(d)
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\t%s\t%s\n", name, br.SuccessfulRequests, br.TotalRequests,
+			formatDuration(br.AvgTotalTime), formatDuration(br.P95TotalTime), chunkLatency)
+	}
+	w.Flush()
+
+	fmt.Printf("Max concurrent streams observed: %d\n", r.MaxConcurrentStreams)
+	fmt.Printf("└─────────────────────────────────────────────────────────────────┘\n")
+}
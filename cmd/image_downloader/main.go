@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,6 +24,10 @@ const (
 	largeCount  = 5
 	smallSize   = 300  // 300x300 pixels for small images
 	largeSize   = 2400 // 2400x2400 pixels for large images
+
+	maxRetries     = 5
+	baseRetryDelay = 500 * time.Millisecond
+	maxRetryDelay  = 30 * time.Second
 )
 
 // ImageConfig holds the details for a single image to be downloaded.
@@ -39,29 +47,51 @@ func main() {
 		log.Fatalf("Error creating output directory %s: %v", outputDir, err)
 	}
 
-	// Channel to manage the list of image configurations
-	imageJobs := make(chan ImageConfig, totalImages)
-	// WaitGroup to wait for all goroutines to finish
-	var wg sync.WaitGroup
+	jobs := buildJobs()
 
-	// Set a reasonable concurrency limit
 	numWorkers := 10
-	if totalImages < numWorkers {
-		numWorkers = totalImages
+	if len(jobs) < numWorkers {
+		numWorkers = len(jobs)
+	}
+
+	ctx := context.Background()
+	jobCh := make(chan ImageConfig, len(jobs))
+	for _, job := range jobs {
+		jobCh <- job
 	}
+	close(jobCh)
 
-	// Start worker goroutines
+	// Workers log and move on when an image exhausts its retries instead of aborting the whole
+	// batch: one flaky image shouldn't cost the other 99 their downloads.
+	var wg sync.WaitGroup
+	var failedCount int64
 	for i := 0; i < numWorkers; i++ {
+		workerID := i
+		// Each worker reuses a single http.Client so keep-alives are actually kept alive,
+		// instead of paying a fresh TLS handshake per image.
+		client := &http.Client{Timeout: 60 * time.Second}
 		wg.Add(1)
-		go worker(i, imageJobs, &wg)
+		go func() {
+			defer wg.Done()
+			worker(ctx, workerID, client, jobCh, &failedCount)
+		}()
+	}
+	wg.Wait()
+
+	if failedCount > 0 {
+		log.Fatalf("%d/%d images failed after exhausting retries; see log above for details.", failedCount, len(jobs))
 	}
+	fmt.Println("\nAll downloads finished successfully!")
+}
 
-	// Queue up the download jobs
+// buildJobs constructs the fixed set of image download jobs: the first largeCount images are
+// large, the rest small, matching the corpus shape the benchmark server expects.
+func buildJobs() []ImageConfig {
+	jobs := make([]ImageConfig, 0, totalImages)
 	for i := 0; i < totalImages; i++ {
 		var imgSize int
 		var sizeLabel string
 
-		// First 5 images are large, rest are small
 		if i < largeCount {
 			imgSize = largeSize
 			sizeLabel = "large"
@@ -73,77 +103,189 @@ func main() {
 		filename := fmt.Sprintf("image_%03d_%s_%dx%d.jpg", i+1, sizeLabel, imgSize, imgSize)
 		url := fmt.Sprintf(baseURL, imgSize, imgSize)
 
-		// Add a random query parameter to prevent the same image from being cached
-		url = fmt.Sprintf("%s?random=%d", url, time.Now().UnixNano()/int64(time.Millisecond)+int64(i))
+		// Add a cache-busting query parameter so picsum doesn't serve the same cached image for
+		// every size/position combination. This must be deterministic per job ID rather than
+		// derived from the current time: downloadFile resumes a .part file by byte offset alone,
+		// so a restarted process that picked a new random image for the same job would send a
+		// Range request against a *different* image and silently splice the two together.
+		url = fmt.Sprintf("%s?random=%d", url, i+1)
 
-		imageJobs <- ImageConfig{
+		jobs = append(jobs, ImageConfig{
 			ID:   i + 1,
 			URL:  url,
 			Path: filepath.Join(outputDir, filename),
-		}
+		})
 	}
-	close(imageJobs) // Close the channel to signal workers no more jobs will be added
-
-	// Wait for all workers to complete
-	wg.Wait()
-	fmt.Println("\nAll downloads finished successfully!")
+	return jobs
 }
 
-// worker is a goroutine that pulls download jobs from the channel and executes them.
-func worker(id int, jobs <-chan ImageConfig, wg *sync.WaitGroup) {
-	defer wg.Done()
+// worker pulls download jobs from the channel and executes them, retrying transient failures
+// with exponential backoff. An image that still fails after exhausting its retries is logged and
+// skipped rather than aborting the rest of the batch; failed tallies the total for main to report.
+func worker(ctx context.Context, id int, client *http.Client, jobs <-chan ImageConfig, failed *int64) {
 	log.Printf("Worker %d started.", id)
+	defer log.Printf("Worker %d finished.", id)
 
-	// Iterate over the channel until it is closed
 	for job := range jobs {
 		fmt.Printf("Worker %d: Downloading image %d...\n", id, job.ID)
-		if err := downloadFile(job.URL, job.Path); err != nil {
-			log.Printf("Worker %d: FAILED to download image %d from %s: %v", id, job.ID, job.URL, err)
-		} else {
-			fmt.Printf("Worker %d: Successfully saved image %d to %s\n", id, job.ID, job.Path)
+		if err := downloadWithRetry(ctx, client, job); err != nil {
+			log.Printf("Worker %d: image %d from %s failed after retries: %v", id, job.ID, job.URL, err)
+			atomic.AddInt64(failed, 1)
+			continue
 		}
+		fmt.Printf("Worker %d: Successfully saved image %d to %s\n", id, job.ID, job.Path)
 	}
-	log.Printf("Worker %d finished.", id)
 }
 
-// downloadFile performs the actual HTTP request and saves the body to a file.
-func downloadFile(url string, filepath string) error {
-	// Create a new HTTP client with a reasonable timeout
-	client := http.Client{
-		Timeout: 30 * time.Second,
+// downloadWithRetry calls downloadFile, retrying up to maxRetries times with exponential
+// backoff and jitter on any error it returns (network, non-2xx status or local file errors alike).
+func downloadWithRetry(ctx context.Context, client *http.Client, job ImageConfig) error {
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := backoffWithJitter(attempt)
+			log.Printf("Retrying image %d (attempt %d/%d) after %v: %v", job.ID, attempt, maxRetries, delay, lastErr)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := downloadFile(ctx, client, job.URL, job.Path); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
 	}
+	return fmt.Errorf("exhausted %d retries: %w", maxRetries, lastErr)
+}
 
-	// Create request with context for cancellation/timeouts
-	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
-	defer cancel()
+func backoffWithJitter(attempt int) time.Duration {
+	delay := baseRetryDelay * time.Duration(1<<uint(attempt-1))
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay) / 2))
+	return delay/2 + jitter
+}
+
+// downloadFile resumes a partial download when the server supports Range requests, records a
+// streaming SHA-256 of the result in a sidecar .sha256 file, and skips entirely when that sidecar
+// already matches a complete download on disk.
+func downloadFile(ctx context.Context, client *http.Client, url, path string) error {
+	partPath := path + ".part"
+	sumPath := path + ".sha256"
+
+	if alreadyComplete(path, sumPath) {
+		return nil
+	}
+
+	acceptsRanges := probeAcceptRanges(ctx, client, url)
+
+	var offset int64
+	flags := os.O_CREATE | os.O_WRONLY
+	if acceptsRanges {
+		if info, err := os.Stat(partPath); err == nil {
+			offset = info.Size()
+			flags |= os.O_APPEND
+		}
+	} else {
+		// Server can't resume; always restart from scratch.
+		os.Remove(partPath)
+		flags |= os.O_TRUNC
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return fmt.Errorf("could not create request: %w", err)
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
-	// Perform the GET request
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("http request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("received non-200 response status: %s", resp.Status)
+	if offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// Our partial file is already complete (or the server disagrees on size); restart clean.
+		os.Remove(partPath)
+		return downloadFile(ctx, client, url, path)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("received non-2xx response status: %s", resp.Status)
 	}
 
-	// Create the output file
-	out, err := os.Create(filepath)
+	out, err := os.OpenFile(partPath, flags, 0644)
 	if err != nil {
-		return fmt.Errorf("could not create file: %w", err)
+		return fmt.Errorf("could not open part file: %w", err)
 	}
-	defer out.Close()
 
-	// Use io.Copy to efficiently stream the response body to the file
-	if _, err := io.Copy(out, resp.Body); err != nil {
+	hasher := sha256.New()
+	if offset > 0 {
+		if existing, err := os.Open(partPath); err == nil {
+			io.CopyN(hasher, existing, offset)
+			existing.Close()
+		}
+	}
+
+	writer := io.MultiWriter(out, hasher)
+	if _, err := io.Copy(writer, resp.Body); err != nil {
+		out.Close()
 		return fmt.Errorf("error writing to file: %w", err)
 	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if err := os.WriteFile(sumPath, []byte(sum+"\n"), 0644); err != nil {
+		return fmt.Errorf("could not write checksum sidecar: %w", err)
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return fmt.Errorf("could not finalize downloaded file: %w", err)
+	}
 
 	return nil
 }
+
+// alreadyComplete reports whether path exists and its sidecar checksum matches its contents,
+// letting repeat runs skip re-downloading unchanged images.
+func alreadyComplete(path, sumPath string) bool {
+	wantBytes, err := os.ReadFile(sumPath)
+	if err != nil {
+		return false
+	}
+	want := string(wantBytes)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return false
+	}
+	got := hex.EncodeToString(hasher.Sum(nil)) + "\n"
+	return got == want
+}
+
+// probeAcceptRanges issues a HEAD request to check whether the server advertises
+// Accept-Ranges: bytes, which is required before we attempt to resume a partial download.
+func probeAcceptRanges(ctx context.Context, client *http.Client, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.Header.Get("Accept-Ranges") == "bytes"
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is used when SHUTDOWN_TIMEOUT is unset or invalid.
+const defaultShutdownTimeout = 30 * time.Second
+
+// managedServer pairs a blocking start function with a shutdown function, letting runServers
+// drain arbitrary listeners (*http.Server, *http3.Server, ...) uniformly.
+type managedServer struct {
+	name     string
+	start    func() error
+	shutdown func(ctx context.Context) error
+}
+
+// serverStarter adapts (*http.Server).ListenAndServe to the managedServer start signature,
+// treating the expected post-Shutdown error as success.
+func serverStarter(server *http.Server) func() error {
+	return func() error {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// serverTLSStarter is the TLS equivalent of serverStarter, for servers using
+// ListenAndServeTLS with an in-memory certificate (empty cert/key file paths).
+func serverTLSStarter(server *http.Server) func() error {
+	return func() error {
+		if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// shutdownTimeout reads SHUTDOWN_TIMEOUT (a Go duration string, e.g. "30s") from the
+// environment, falling back to defaultShutdownTimeout.
+func shutdownTimeout() time.Duration {
+	v := os.Getenv("SHUTDOWN_TIMEOUT")
+	if v == "" {
+		return defaultShutdownTimeout
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid SHUTDOWN_TIMEOUT=%q, using default %s", v, defaultShutdownTimeout)
+		return defaultShutdownTimeout
+	}
+	return d
+}
+
+// runServers starts every server's start func in its own goroutine, then blocks until either a
+// SIGINT/SIGTERM is received or one of the servers returns a fatal error. Either way, it drains
+// every server via its shutdown func (bounded by SHUTDOWN_TIMEOUT) before returning, so rolling
+// deploys on Fly.io/Cloud Run don't drop in-flight requests. It returns the first fatal error
+// encountered, or nil on a clean signal-triggered shutdown.
+func runServers(servers []managedServer) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	fatalCh := make(chan error, len(servers))
+
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s managedServer) {
+			defer wg.Done()
+			if err := s.start(); err != nil {
+				fatalCh <- err
+			}
+		}(s)
+	}
+
+	var fatalErr error
+	select {
+	case sig := <-sigCh:
+		log.Printf("Received signal %v, draining servers...", sig)
+	case fatalErr = <-fatalCh:
+		log.Printf("Server failed, draining remaining servers: %v", fatalErr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancel()
+
+	var shutdownWg sync.WaitGroup
+	for _, s := range servers {
+		shutdownWg.Add(1)
+		go func(s managedServer) {
+			defer shutdownWg.Done()
+			if err := s.shutdown(ctx); err != nil {
+				log.Printf("%s: shutdown error: %v", s.name, err)
+			}
+		}(s)
+	}
+	shutdownWg.Wait()
+
+	wg.Wait()
+	return fatalErr
+}
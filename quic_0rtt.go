@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	sessionTicketKeyFile   = "certs/session-ticket.key"
+	sessionTicketKeyRotate = 12 * time.Hour
+	replayCacheTTL         = 60 * time.Second
+)
+
+// loadOrCreateSessionTicketKey reads a persistent 32-byte TLS session ticket key from path,
+// generating and saving one if it doesn't exist yet. Without this, tls.Config picks a random
+// key on every process start, which invalidates every outstanding session ticket (and thus
+// 0-RTT) across a restart.
+func loadOrCreateSessionTicketKey(path string) ([32]byte, error) {
+	var key [32]byte
+
+	if data, err := os.ReadFile(path); err == nil && len(data) == len(key) {
+		copy(key[:], data)
+		return key, nil
+	}
+
+	if _, err := rand.Read(key[:]); err != nil {
+		return key, fmt.Errorf("generating session ticket key: %w", err)
+	}
+	if err := os.WriteFile(path, key[:], 0600); err != nil {
+		log.Printf("Note: could not persist session ticket key to %s: %v", path, err)
+	}
+	return key, nil
+}
+
+// rotateSessionTicketKeys periodically generates a fresh session ticket key and installs it via
+// cfg.SetSessionTicketKeys, keeping the previous key around for one rotation period so tickets
+// issued just before a rotation can still be redeemed.
+func rotateSessionTicketKeys(cfg sessionTicketSetter, initial [32]byte) {
+	current := initial
+	ticker := time.NewTicker(sessionTicketKeyRotate)
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			var next [32]byte
+			if _, err := rand.Read(next[:]); err != nil {
+				log.Printf("session ticket key rotation: %v", err)
+				continue
+			}
+			cfg.SetSessionTicketKeys([][32]byte{next, current})
+			current = next
+		}
+	}()
+}
+
+// sessionTicketSetter is the subset of *tls.Config used by rotateSessionTicketKeys, so tests
+// (and callers without a real tls.Config) can supply a stand-in.
+type sessionTicketSetter interface {
+	SetSessionTicketKeys(keys [][32]byte)
+}
+
+// replayCache rejects 0-RTT requests that replay an earlier early-data request, keyed on a hash
+// of the request (see requestHash) so a duplicate early-data GET/HEAD within the same
+// replayCacheTTL time bucket is refused rather than served twice. Non-idempotent methods never
+// reach it: early0RTTGuard refuses them outright.
+type replayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{seen: make(map[string]time.Time)}
+}
+
+// seenBefore reports whether requestHash was already recorded within the TTL, recording it
+// (and sweeping expired entries) as a side effect.
+func (c *replayCache) seenBefore(requestHash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for h, t := range c.seen {
+		if now.Sub(t) > replayCacheTTL {
+			delete(c.seen, h)
+		}
+	}
+
+	if _, ok := c.seen[requestHash]; ok {
+		return true
+	}
+	c.seen[requestHash] = now
+	return false
+}
+
+// requestHash identifies a 0-RTT request for replay detection. Method and path alone would
+// collide for any two distinct clients sending the same safe request (e.g. GET /) within the
+// same time bucket, rejecting ordinary concurrent traffic as "replayed"; folding in the QUIC
+// connection identity ties the hash to one specific early-data session, so only a genuine resend
+// on that same connection collides.
+func requestHash(r *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte(r.URL.String()))
+	h.Write([]byte(connectionIdentity(r)))
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(time.Now().Truncate(replayCacheTTL).UnixNano()))
+	h.Write(buf[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// connectionIdentity returns a stable identifier for the QUIC connection r arrived on (the
+// connection h3ConnContext attached to its context), falling back to the TCP/UDP remote address
+// if that's somehow unavailable. Either is connection-specific in a way "method + path" alone
+// isn't.
+func connectionIdentity(r *http.Request) string {
+	if conn, ok := r.Context().Value(quicConnCtxKey{}).(quic.Connection); ok {
+		return fmt.Sprintf("%p", conn)
+	}
+	return r.RemoteAddr
+}
+
+// quicConnCtxKey is the context key h3ConnContext stashes the accepting quic.Connection under, so
+// requestUsed0RTT can look up its ConnectionState. r.TLS (the stdlib *tls.ConnectionState) has no
+// 0-RTT field at all; that only exists on quic-go's own quic.ConnectionState, which isn't reachable
+// from a *http.Request without this plumbing.
+type quicConnCtxKey struct{}
+
+// h3ConnContext is installed as http3.Server.ConnContext so every request's context carries the
+// quic.Connection it arrived on, which is the only place Used0RTT is actually observable.
+func h3ConnContext(ctx context.Context, c quic.Connection) context.Context {
+	return context.WithValue(ctx, quicConnCtxKey{}, c)
+}
+
+// requestUsed0RTT reports whether r arrived as TLS/QUIC early data, by reading the ConnectionState
+// of the quic.Connection that h3ConnContext attached to the request's context.
+func requestUsed0RTT(r *http.Request) bool {
+	conn, ok := r.Context().Value(quicConnCtxKey{}).(quic.Connection)
+	if !ok {
+		return false
+	}
+	return conn.ConnectionState().Used0RTT
+}
+
+// early0RTTGuard restricts 0-RTT requests to safe, idempotent methods and paths, and rejects
+// replays of the same early-data request within the cache TTL. Non-0-RTT requests pass through
+// unmodified.
+func early0RTTGuard(next http.Handler, cache *replayCache, idempotentPaths map[string]bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requestUsed0RTT(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusTooEarly)
+			return
+		}
+		if idempotentPaths != nil && !idempotentPaths[r.URL.Path] {
+			w.WriteHeader(http.StatusTooEarly)
+			return
+		}
+		if cache.seenBefore(requestHash(r)) {
+			w.WriteHeader(http.StatusTooEarly)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
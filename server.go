@@ -5,7 +5,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"sync"
 
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
@@ -35,6 +34,8 @@ func main() {
 		runFlyioMode()
 	case "cloudrun":
 		runCloudRunMode()
+	case "bench-client":
+		runBenchmarkClient()
 	default:
 		runBenchmarkMode()
 	}
@@ -85,10 +86,15 @@ func runFlyioMode() {
 	// Wrap file server with no-cache middleware
 	mux.Handle("/", noCacheMiddleware(http.FileServer(http.Dir(STATIC_DIR))))
 
+	// Advertise HTTP/3 at the edge so browsers upgrade to QUIC. Fly.io terminates QUIC on the
+	// same public port as HTTP/1.1 and HTTP/2, so H3_PORT should be set to that port (e.g. 443)
+	// in production; it's left unset (and the header suppressed) by default for local testing.
+	altSvc := altSvcMiddleware(os.Getenv("H3_PORT"))
+
 	// Wrap handler with h2c to support HTTP/2 cleartext (unencrypted HTTP/2)
 	// This allows Fly.io's proxy to communicate with our server over HTTP/2
 	h2s := &http2.Server{}
-	h2cHandler := h2c.NewHandler(mux, h2s)
+	h2cHandler := h2c.NewHandler(altSvc(mux), h2s)
 
 	addr := ":" + port
 	log.Printf("[Fly.io Mode] Starting HTTP/1.1 + h2c server on %s", addr)
@@ -101,7 +107,10 @@ func runFlyioMode() {
 		Handler: h2cHandler,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
+	err := runServers([]managedServer{
+		{name: "http", start: serverStarter(server), shutdown: server.Shutdown},
+	})
+	if err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -123,7 +132,12 @@ func runCloudRunMode() {
 	log.Printf("Note: HTTP/3 is NOT supported on Cloud Run")
 	log.Printf("Cache-Control: no-store enabled for all responses")
 
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	err := runServers([]managedServer{
+		{name: "http", start: serverStarter(server), shutdown: server.Shutdown},
+	})
+	if err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -147,82 +161,71 @@ func runBenchmarkMode() {
 	// Wrap file server with no-cache middleware
 	mux.Handle("/", noCacheMiddleware(http.FileServer(http.Dir(STATIC_DIR))))
 
-	var wg sync.WaitGroup
+	// The H1/H2 listeners advertise H3_PORT via Alt-Svc so clients upgrade to QUIC; the H3
+	// listener's handler (below) omits Alt-Svc since it has nothing further to advertise.
+	h1h2Handler := altSvcMiddleware(h3Port)(mux)
 
-	// --- HTTP/1.1 Server (plain HTTP) ---
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		h1Addr := ":" + h1Port
-		log.Printf("Starting HTTP/1.1 server on %s", h1Addr)
-		if err := http.ListenAndServe(h1Addr, mux); err != nil {
-			log.Printf("HTTP/1.1 server error: %v", err)
-		}
-	}()
-
-	// Check if certificates exist
-	if _, err := os.Stat(CERT_FILE); os.IsNotExist(err) {
-		log.Printf("Warning: Certificate file %s not found. HTTP/2 and HTTP/3 servers will not start.", CERT_FILE)
-		log.Printf("Generate certificates with: openssl req -x509 -newkey rsa:4096 -keyout %s -out %s -days 365 -nodes -subj \"/CN=localhost\"", KEY_FILE, CERT_FILE)
-		wg.Wait()
-		return
-	}
-
-	cert, err := tls.LoadX509KeyPair(CERT_FILE, KEY_FILE)
+	// Load the configured certificate, or generate an ephemeral self-signed one so HTTP/2 and
+	// HTTP/3 still start without requiring openssl to be installed.
+	cert, err := loadOrGenerateCert(CERT_FILE, KEY_FILE)
 	if err != nil {
-		log.Fatalf("Failed to load TLS certificate: %v", err)
+		log.Fatalf("Failed to load or generate TLS certificate: %v", err)
 	}
 
-	// --- HTTP/2 Server (HTTPS with TLS) ---
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		h2Addr := ":" + h2Port
-		log.Printf("Starting HTTP/2 server (TLS) on %s", h2Addr)
+	// --- HTTP/1.1 Server (plain HTTP) ---
+	h1Addr := ":" + h1Port
+	h1Server := &http.Server{Addr: h1Addr, Handler: h1h2Handler}
 
-		tlsConfig := &tls.Config{
+	// --- HTTP/2 Server (HTTPS with TLS) ---
+	h2Addr := ":" + h2Port
+	h2Server := &http.Server{
+		Addr:    h2Addr,
+		Handler: h1h2Handler,
+		TLSConfig: &tls.Config{
 			Certificates: []tls.Certificate{cert},
 			NextProtos:   []string{"h2"},
-		}
-
-		server := &http.Server{
-			Addr:      h2Addr,
-			Handler:   mux,
-			TLSConfig: tlsConfig,
-		}
-		if err := server.ListenAndServeTLS("", ""); err != nil {
-			log.Printf("HTTP/2 server error: %v", err)
-		}
-	}()
+		},
+	}
 
 	// --- HTTP/3 Server (QUIC) with 0-RTT enabled ---
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		h3Addr := ":" + h3Port
-		log.Printf("Starting HTTP/3 server (QUIC) with 0-RTT on %s", h3Addr)
-
-		// Configure QUIC with 0-RTT (early data) support
-		quicConfig := &quic.Config{
+	h3Addr := ":" + h3Port
+
+	// Restrict 0-RTT requests to safe, idempotent endpoints and reject replays, since
+	// early data can be replayed by an attacker before the handshake completes.
+	replayCache := newReplayCache()
+	h3Handler := early0RTTGuard(mux, replayCache, map[string]bool{"/": true, "/health": true})
+
+	h3TLSConfig := http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}})
+	ticketKey, err := loadOrCreateSessionTicketKey(sessionTicketKeyFile)
+	if err != nil {
+		log.Printf("HTTP/3 session ticket key error: %v", err)
+	} else {
+		h3TLSConfig.SetSessionTicketKeys([][32]byte{ticketKey})
+		rotateSessionTicketKeys(h3TLSConfig, ticketKey)
+	}
+
+	h3Server := &http3.Server{
+		Addr:        h3Addr,
+		Handler:     h3Handler,
+		TLSConfig:   h3TLSConfig,
+		ConnContext: h3ConnContext,
+		QUICConfig: &quic.Config{
 			Allow0RTT: true, // Enable 0-RTT for faster connection resumption
-		}
-
-		h3Server := &http3.Server{
-			Addr:       h3Addr,
-			Handler:    mux,
-			TLSConfig:  http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
-			QUICConfig: quicConfig,
-		}
-		if err := h3Server.ListenAndServe(); err != nil {
-			log.Printf("HTTP/3 server error: %v", err)
-		}
-	}()
-
-	log.Println("[Benchmark Mode] All servers started. Press Ctrl+C to stop.")
+		},
+	}
+
+	log.Println("[Benchmark Mode] Starting all servers. Press Ctrl+C to stop.")
 	log.Printf("  HTTP/1.1: http://localhost:%s", h1Port)
 	log.Printf("  HTTP/2:   https://localhost:%s", h2Port)
 	log.Printf("  HTTP/3:   https://localhost:%s (QUIC/UDP with 0-RTT)", h3Port)
 	log.Printf("  Cache-Control: no-store enabled for all responses")
 
-	wg.Wait()
+	runErr := runServers([]managedServer{
+		{name: "http1", start: serverStarter(h1Server), shutdown: h1Server.Shutdown},
+		{name: "http2", start: serverTLSStarter(h2Server), shutdown: h2Server.Shutdown},
+		{name: "http3", start: h3Server.ListenAndServe, shutdown: h3Server.Shutdown},
+	})
+	if runErr != nil {
+		log.Fatalf("Server error: %v", runErr)
+	}
 }